@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent records a change made to a chamber through the realm server
+type AuditEvent struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// RequestID is the ID of the request that made the change, as returned in its X-Request-Id header
+	RequestID string `json:"requestId"`
+	// Identity is the authenticated caller that made the change, it is empty when the server does not authenticate requests
+	Identity   string `json:"identity,omitempty"`
+	AuthMethod string `json:"authMethod,omitempty"`
+	// Operation is one of put, patch or delete
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	// RuleKey is the key of the rule when a single rule was changed
+	RuleKey string `json:"ruleKey,omitempty"`
+	// Revision is the revision of the chamber after the change, it is empty when the chamber was deleted
+	Revision string `json:"revision,omitempty"`
+	// Message is the reason for the change supplied by the caller in the X-Realm-Change-Message header
+	Message string       `json:"message,omitempty"`
+	Changes []RuleChange `json:"changes"`
+}
+
+// RuleChangeType is how a rule was changed
+type RuleChangeType string
+
+const (
+	RuleAdded    RuleChangeType = "added"
+	RuleModified RuleChangeType = "modified"
+	RuleRemoved  RuleChangeType = "removed"
+)
+
+// RuleChange is the change of a single rule of a chamber
+type RuleChange struct {
+	Key    string          `json:"key"`
+	Change RuleChangeType  `json:"change"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
@@ -0,0 +1,34 @@
+package api
+
+// EvaluationRequest asks the realm server to evaluate the rules of a chamber the same way the SDK does
+type EvaluationRequest struct {
+	// Path is the path of the chamber whose rules are evaluated
+	Path string `json:"path"`
+	// Version is the application version overrides are evaluated at. Only the rules' own values are returned when it is empty
+	Version string `json:"version,omitempty"`
+	// Context holds the attributes of the evaluation context like realm.EvaluationContext does.
+	// As with the SDK, only the version currently affects how rules resolve
+	Context map[string]string `json:"context,omitempty"`
+	// Keys limits the evaluation to the rules with these keys, every rule of the chamber is evaluated when it is empty
+	Keys []string `json:"keys,omitempty"`
+}
+
+// EvaluationResponse holds the resolved rules of a chamber
+type EvaluationResponse struct {
+	Path string `json:"path"`
+	// Revision is the revision of the chamber the rules were evaluated with
+	Revision string `json:"revision"`
+	// Rules maps the key of every evaluated rule to its resolved value
+	Rules map[string]EvaluatedRule `json:"rules"`
+}
+
+// EvaluatedRule is the resolved value of a rule along with why it was resolved
+type EvaluatedRule struct {
+	Type  string `json:"type,omitempty"`
+	Value any    `json:"value,omitempty"`
+	// Reason is one of STATIC, DEFAULT, TARGETING_MATCH or ERROR
+	Reason  string `json:"reason"`
+	Variant string `json:"variant,omitempty"`
+	// Error describes why the rule could not be evaluated when Reason is ERROR
+	Error string `json:"error,omitempty"`
+}
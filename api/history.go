@@ -0,0 +1,20 @@
+package api
+
+import "time"
+
+// ChamberRevision describes a revision in the history of a chamber
+type ChamberRevision struct {
+	// Revision is the revision of the chamber's content, as returned in the ETag header of chamber requests
+	Revision string    `json:"revision"`
+	Time     time.Time `json:"time"`
+	// Identity is the authenticated caller that wrote the revision, if any
+	Identity  string `json:"identity,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	// Deleted is true for the revision recording the deletion of the chamber, which has no content
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// RollbackRequest restores the chamber to the content of one of its revisions
+type RollbackRequest struct {
+	Revision string `json:"revision"`
+}
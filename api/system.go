@@ -0,0 +1,12 @@
+package api
+
+// HealthStatus is the status returned by the liveness and readiness endpoints
+type HealthStatus struct {
+	Status string `json:"status"`
+}
+
+// VersionInfo describes the build of the server
+type VersionInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion"`
+}
@@ -0,0 +1,22 @@
+package api
+
+import "time"
+
+// UsageReport is sent by SDKs to report how many times the rules of each chamber were evaluated since the previous report
+type UsageReport struct {
+	// Application is the name of the application that evaluated the rules
+	Application string `json:"application"`
+	// Version is the version of the application that evaluated the rules
+	Version string `json:"version,omitempty"`
+	// Chambers maps a chamber path to the number of evaluations of each of its rules
+	Chambers map[string]map[string]int64 `json:"chambers"`
+}
+
+// RuleUsage is the usage record the server keeps for a rule of a chamber
+type RuleUsage struct {
+	// Count is the total number of evaluations reported for the rule
+	Count                int64     `json:"count"`
+	LastEvaluatedAt      time.Time `json:"lastEvaluatedAt,omitzero"`
+	LastEvaluatedBy      string    `json:"lastEvaluatedBy,omitempty"`
+	LastEvaluatedVersion string    `json:"lastEvaluatedVersion,omitempty"`
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	realm "github.com/steviebps/realm/pkg"
+)
+
+// ResponseError is returned when the realm server responds with an error status or reports errors in its response
+type ResponseError struct {
+	StatusCode int
+	// Errors holds the error messages reported by the realm server
+	Errors []string
+}
+
+func (re *ResponseError) Error() string {
+	if len(re.Errors) == 0 {
+		return fmt.Sprintf("realm server responded with %d %s", re.StatusCode, http.StatusText(re.StatusCode))
+	}
+	return fmt.Sprintf("realm server responded with %d: %s", re.StatusCode, strings.Join(re.Errors, "; "))
+}
+
+// IsNotFound reports whether err is a ResponseError for a resource that does not exist
+func IsNotFound(err error) bool {
+	var re *ResponseError
+	return errors.As(err, &re) && re.StatusCode == http.StatusNotFound
+}
+
+// GetChamber retrieves the chamber at path
+func (c *HttpClient) GetChamber(ctx context.Context, path string) (*realm.Chamber, error) {
+	data, err := c.performChamberRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var chamber realm.Chamber
+	if err := json.Unmarshal(data, &chamber); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chamber %q: %w", path, err)
+	}
+	return &chamber, nil
+}
+
+// PutChamber creates or replaces the chamber at path. It is sent as a PUT so that it is retried like other idempotent requests
+func (c *HttpClient) PutChamber(ctx context.Context, path string, chamber *realm.Chamber) error {
+	_, err := c.performChamberRequest(ctx, http.MethodPut, path, chamber)
+	return err
+}
+
+// PatchChamber merges the rules of chamber into the existing chamber at path
+func (c *HttpClient) PatchChamber(ctx context.Context, path string, chamber *realm.Chamber) error {
+	_, err := c.performChamberRequest(ctx, http.MethodPatch, path, chamber)
+	return err
+}
+
+// DeleteChamber deletes the chamber at path
+func (c *HttpClient) DeleteChamber(ctx context.Context, path string) error {
+	_, err := c.performChamberRequest(ctx, http.MethodDelete, path, nil)
+	return err
+}
+
+// ListChambers returns the names of the chambers directly under path
+func (c *HttpClient) ListChambers(ctx context.Context, path string) ([]string, error) {
+	data, err := c.performChamberRequest(ctx, http.MethodGet, strings.TrimSuffix(path, "/")+"/?list=true", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chambers listed at %q: %w", path, err)
+	}
+	return names, nil
+}
+
+// GetRule retrieves the rule with key from the chamber at path
+func (c *HttpClient) GetRule(ctx context.Context, path string, key string) (*realm.OverrideableRule, error) {
+	data, err := c.performAPIRequest(ctx, http.MethodGet, ruleEndpoint(path, key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rule realm.OverrideableRule
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return nil, fmt.Errorf("could not unmarshal rule %q of %q: %w", key, path, err)
+	}
+	return &rule, nil
+}
+
+// PutRule creates or replaces the rule with key in the existing chamber at path without modifying its other rules
+func (c *HttpClient) PutRule(ctx context.Context, path string, key string, rule *realm.OverrideableRule) error {
+	_, err := c.performAPIRequest(ctx, http.MethodPut, ruleEndpoint(path, key), rule)
+	return err
+}
+
+// DeleteRule deletes the rule with key from the chamber at path without modifying its other rules
+func (c *HttpClient) DeleteRule(ctx context.Context, path string, key string) error {
+	_, err := c.performAPIRequest(ctx, http.MethodDelete, ruleEndpoint(path, key), nil)
+	return err
+}
+
+func ruleEndpoint(path string, key string) string {
+	return "/v1/rules/" + strings.TrimPrefix(path, "/") + "?key=" + url.QueryEscape(key)
+}
+
+// performChamberRequest performs a request to the chambers endpoint with body encoded as JSON
+// and returns the data of the response or a ResponseError if the server reported errors
+func (c *HttpClient) performChamberRequest(ctx context.Context, method string, path string, body any) (json.RawMessage, error) {
+	return c.performAPIRequest(ctx, method, "/v1/chambers/"+strings.TrimPrefix(path, "/"), body)
+}
+
+// performAPIRequest performs a request to the endpoint of the realm server, e.g. /v1/history/app, with body encoded as JSON
+// and returns the data of the response or a ResponseError if the server reported errors
+func (c *HttpClient) performAPIRequest(ctx context.Context, method string, endpoint string, body any) (json.RawMessage, error) {
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("method", method).Str("endpoint", endpoint).Msg("performing a new request")
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal request body for %q: %w", endpoint, err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := c.newRequest(ctx, method, endpoint, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var httpRes api.HTTPErrorAndDataResponse
+	if res.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(res.Body).Decode(&httpRes); err != nil && !errors.Is(err, io.EOF) {
+			if res.StatusCode >= http.StatusBadRequest {
+				return nil, &ResponseError{StatusCode: res.StatusCode}
+			}
+			return nil, fmt.Errorf("could not read response for %q: %w", endpoint, err)
+		}
+	}
+
+	if res.StatusCode >= http.StatusBadRequest || len(httpRes.Errors) > 0 {
+		err := &ResponseError{StatusCode: res.StatusCode, Errors: httpRes.Errors}
+		logger.DebugCtx(ctx).Str("method", method).Str("endpoint", endpoint).Str("error", err.Error()).Msg("request failed")
+		return nil, err
+	}
+	return httpRes.Data, nil
+}
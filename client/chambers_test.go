@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestChambers(t *testing.T) {
+	srv := realmtest.NewServer(t, nil)
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	chamber := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}},
+	}}
+	if err := c.PutChamber(ctx, "app", chamber); err != nil {
+		t.Fatal(err)
+	}
+	patch := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"message": {Rule: &realm.Rule{Type: "string", Value: "hello"}},
+	}}
+	if err := c.PatchChamber(ctx, "/app/", patch); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetChamber(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rules["enabled"] == nil || got.Rules["message"] == nil {
+		t.Errorf("unexpected chamber: %v", got.Rules)
+	}
+
+	names, err := c.ListChambers(ctx, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(names, "app") {
+		t.Errorf("expected app to be listed but listed %q", names)
+	}
+
+	if err := c.DeleteChamber(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		do     func() error
+		status int
+	}{
+		{"get deleted chamber", func() error { _, err := c.GetChamber(ctx, "app"); return err }, http.StatusNotFound},
+		{"delete missing chamber", func() error { return c.DeleteChamber(ctx, "app") }, http.StatusNotFound},
+		{"patch missing chamber", func() error { return c.PatchChamber(ctx, "app", patch) }, http.StatusBadRequest},
+		{"put invalid chamber", func() error {
+			return c.PutChamber(ctx, "app", &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"bad": {Rule: &realm.Rule{Type: "boolean", Value: "yes"}}}})
+		}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+			var re *ResponseError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected ResponseError but returned: %v", err)
+			}
+			if re.StatusCode != tt.status || len(re.Errors) == 0 {
+				t.Errorf("expected status %d with errors but returned %d %q", tt.status, re.StatusCode, re.Errors)
+			}
+		})
+	}
+	if !IsNotFound(c.DeleteChamber(ctx, "app")) {
+		t.Errorf("expected a not found error")
+	}
+}
+
+func TestRules(t *testing.T) {
+	srv := realmtest.NewServer(t, map[string]*realm.Chamber{
+		"/app/": {Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}}}},
+	})
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// concurrent puts of different rules must not overwrite each other
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	errs := make(chan error, len(keys))
+	for _, key := range keys {
+		go func() {
+			errs <- c.PutRule(ctx, "app", key, &realm.OverrideableRule{Rule: &realm.Rule{Type: "string", Value: key}})
+		}()
+	}
+	for range keys {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := c.GetChamber(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Rules) != len(keys)+1 {
+		t.Errorf("expected %d rules but returned %d", len(keys)+1, len(got.Rules))
+	}
+
+	rule, err := c.GetRule(ctx, "app", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Value != "c" {
+		t.Errorf("expected rule value %q but returned %v", "c", rule.Value)
+	}
+
+	if err := c.DeleteRule(ctx, "app", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetRule(ctx, "app", "enabled"); err != nil {
+		t.Errorf("expected the other rules to be kept: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		do     func() error
+		status int
+	}{
+		{"get deleted rule", func() error { _, err := c.GetRule(ctx, "app", "c"); return err }, http.StatusNotFound},
+		{"delete missing rule", func() error { return c.DeleteRule(ctx, "app", "c") }, http.StatusNotFound},
+		{"put rule of missing chamber", func() error {
+			return c.PutRule(ctx, "missing", "enabled", &realm.OverrideableRule{Rule: &realm.Rule{Type: "boolean", Value: true}})
+		}, http.StatusNotFound},
+		{"put invalid rule", func() error {
+			return c.PutRule(ctx, "app", "enabled", &realm.OverrideableRule{Rule: &realm.Rule{Type: "boolean", Value: "yes"}})
+		}, http.StatusBadRequest},
+		{"put empty rule", func() error { return c.PutRule(ctx, "app", "enabled", nil) }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+			var re *ResponseError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected ResponseError but returned: %v", err)
+			}
+			if re.StatusCode != tt.status || len(re.Errors) == 0 {
+				t.Errorf("expected status %d with errors but returned %d %q", tt.status, re.StatusCode, re.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+)
+
+// Evaluate resolves the rules of a chamber on the realm server instead of retrieving the chamber and evaluating it locally
+func (c *HttpClient) Evaluate(ctx context.Context, evaluation api.EvaluationRequest) (*api.EvaluationResponse, error) {
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("path", evaluation.Path).Str("version", evaluation.Version).Msg("evaluating rules")
+
+	b, err := json.Marshal(evaluation)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal evaluation request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/v1/evaluate", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var httpRes api.HTTPErrorAndDataResponse
+	if err := json.NewDecoder(res.Body).Decode(&httpRes); err != nil && !errors.Is(err, io.EOF) {
+		if res.StatusCode >= http.StatusBadRequest {
+			return nil, &ResponseError{StatusCode: res.StatusCode}
+		}
+		return nil, fmt.Errorf("could not read evaluation of %q: %w", evaluation.Path, err)
+	}
+	if res.StatusCode >= http.StatusBadRequest || len(httpRes.Errors) > 0 {
+		return nil, &ResponseError{StatusCode: res.StatusCode, Errors: httpRes.Errors}
+	}
+
+	var evaluated api.EvaluationResponse
+	if err := json.Unmarshal(httpRes.Data, &evaluated); err != nil {
+		return nil, fmt.Errorf("could not unmarshal evaluation of %q: %w", evaluation.Path, err)
+	}
+	return &evaluated, nil
+}
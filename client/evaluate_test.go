@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestEvaluate(t *testing.T) {
+	chamber := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"message": {
+			Rule: &realm.Rule{Type: "string", Value: "default"},
+			Overrides: []*realm.Override{
+				{Rule: &realm.Rule{Type: "string", Value: "v2"}, MinimumVersion: "v2.0.0", MaximumVersion: "v2.9.9"},
+			},
+		},
+		"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}},
+	}}
+	srv := realmtest.NewServer(t, map[string]*realm.Chamber{"/org/": chamber, "/org/app/": {Rules: map[string]*realm.OverrideableRule{}}})
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		req      api.EvaluationRequest
+		expected map[string]api.EvaluatedRule
+	}{
+		{"every rule", api.EvaluationRequest{Path: "org"}, map[string]api.EvaluatedRule{
+			"message": {Type: "string", Value: "default", Reason: "DEFAULT", Variant: realm.VariantDefault},
+			"enabled": {Type: "boolean", Value: true, Reason: "STATIC", Variant: realm.VariantDefault},
+		}},
+		{"override at version", api.EvaluationRequest{Path: "org", Version: "v2.1.0", Keys: []string{"message"}}, map[string]api.EvaluatedRule{
+			"message": {Type: "string", Value: "v2", Reason: "TARGETING_MATCH", Variant: "override[v2.0.0,v2.9.9]"},
+		}},
+		{"inherited rules", api.EvaluationRequest{Path: "org/app", Keys: []string{"enabled", "missing"}}, map[string]api.EvaluatedRule{
+			"enabled": {Type: "boolean", Value: true, Reason: "STATIC", Variant: realm.VariantDefault},
+			"missing": {Reason: "ERROR", Error: "missing does not exist"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.Evaluate(ctx, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Rules) != len(tt.expected) {
+				t.Fatalf("expected %d rules but returned %v", len(tt.expected), res.Rules)
+			}
+			for key, expected := range tt.expected {
+				if got := res.Rules[key]; got != expected {
+					t.Errorf("%s: expected %+v but returned %+v", key, expected, got)
+				}
+			}
+		})
+	}
+
+	res, err := c.Evaluate(ctx, api.EvaluationRequest{Path: "org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Revision != chamber.Revision() {
+		t.Errorf("expected revision %q but returned %q", chamber.Revision(), res.Revision)
+	}
+
+	if _, err := c.Evaluate(ctx, api.EvaluationRequest{Path: "missing"}); !IsNotFound(err) {
+		t.Errorf("expected a not found error but returned %v", err)
+	}
+	var re *ResponseError
+	if _, err := c.Evaluate(ctx, api.EvaluationRequest{}); !errors.As(err, &re) || re.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a bad request error but returned %v", err)
+	}
+}
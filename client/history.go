@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+)
+
+// History returns the revisions of the chamber at path kept by the realm server, most recent first
+func (c *HttpClient) History(ctx context.Context, path string) ([]api.ChamberRevision, error) {
+	data, err := c.performAPIRequest(ctx, http.MethodGet, historyEndpoint(path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []api.ChamberRevision
+	if err := json.Unmarshal(data, &revisions); err != nil {
+		return nil, fmt.Errorf("could not unmarshal history of %q: %w", path, err)
+	}
+	return revisions, nil
+}
+
+// GetChamberAt retrieves the chamber at path as it was at revision
+func (c *HttpClient) GetChamberAt(ctx context.Context, path string, revision string) (*realm.Chamber, error) {
+	data, err := c.performAPIRequest(ctx, http.MethodGet, historyEndpoint(path)+"?revision="+url.QueryEscape(revision), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var chamber realm.Chamber
+	if err := json.Unmarshal(data, &chamber); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chamber %q at revision %q: %w", path, revision, err)
+	}
+	return &chamber, nil
+}
+
+// Rollback restores the chamber at path to its content at revision, which the server records as a new revision
+func (c *HttpClient) Rollback(ctx context.Context, path string, revision string) error {
+	_, err := c.performAPIRequest(ctx, http.MethodPost, historyEndpoint(path), api.RollbackRequest{Revision: revision})
+	return err
+}
+
+func historyEndpoint(path string) string {
+	return "/v1/history/" + strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestHistory(t *testing.T) {
+	// the seeded chamber is written before history is kept, it is recorded when it is first changed
+	v1 := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}}}}
+	srv := realmtest.NewServer(t, map[string]*realm.Chamber{"/app/": v1})
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	v2 := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: false}}}}
+	if err := c.PutChamber(ctx, "app", v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteChamber(ctx, "app"); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := c.History(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || !revisions[0].Deleted || revisions[1].Revision != v2.Revision() || revisions[2].Revision != v1.Revision() {
+		t.Fatalf("unexpected history: %+v", revisions)
+	}
+
+	old, err := c.GetChamberAt(ctx, "app", v1.Revision())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Revision() != v1.Revision() {
+		t.Errorf("expected chamber at revision %s but returned %s", v1.Revision(), old.Revision())
+	}
+
+	// rolling back restores the deleted chamber as a new revision
+	if err := c.Rollback(ctx, "/app/", v1.Revision()); err != nil {
+		t.Fatal(err)
+	}
+	current, err := c.GetChamber(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Revision() != v1.Revision() {
+		t.Errorf("expected rolled back chamber at revision %s but returned %s", v1.Revision(), current.Revision())
+	}
+	revisions, err = c.History(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 4 || revisions[0].Revision != v1.Revision() {
+		t.Errorf("expected the rollback to be the most recent revision: %+v", revisions)
+	}
+
+	tests := []struct {
+		name   string
+		do     func() error
+		status int
+	}{
+		{"get unknown revision", func() error { _, err := c.GetChamberAt(ctx, "app", "0000000000000000"); return err }, http.StatusNotFound},
+		{"rollback to unknown revision", func() error { return c.Rollback(ctx, "app", "0000000000000000") }, http.StatusNotFound},
+		{"rollback without revision", func() error { return c.Rollback(ctx, "app", "") }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.do()
+			var re *ResponseError
+			if !errors.As(err, &re) {
+				t.Fatalf("expected ResponseError but returned: %v", err)
+			}
+			if re.StatusCode != tt.status {
+				t.Errorf("expected status %d but returned %d %q", tt.status, re.StatusCode, re.Errors)
+			}
+		})
+	}
+}
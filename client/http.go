@@ -2,16 +2,19 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/steviebps/realm/helper/logging"
-	"github.com/steviebps/realm/utils"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -19,46 +22,139 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
-const DefaultClientTimeout = 15 * time.Second
+const (
+	DefaultClientTimeout = 15 * time.Second
+	// DefaultRetryWaitMin is the default wait before the first retry
+	DefaultRetryWaitMin = 100 * time.Millisecond
+	// DefaultRetryWaitMax is the default maximum wait between retries
+	DefaultRetryWaitMax = 2 * time.Second
+)
 
 type HttpClientConfig struct {
 	Address string
-	Timeout time.Duration
+	// Addresses are additional realm servers that requests fail over to, in order of preference after Address
+	Addresses []string
+	// SRV is a DNS SRV record name, e.g. _realm._tcp.example.com, whose targets are used as realm servers after Addresses
+	SRV string
+	// SRVScheme is the scheme used to connect to the targets of SRV. DefaultSRVScheme is used when it is empty
+	SRVScheme string
+	Timeout   time.Duration
+	// Token is sent as a bearer token in the Authorization header of every request
+	Token string
+	// Headers are added to every request, e.g. to authenticate with a gateway in front of the realm server
+	Headers map[string]string
+	// CertFile and KeyFile are the paths of a PEM encoded client certificate and key used for mutual TLS
+	CertFile string
+	KeyFile  string
+	// CAFile is the path of a PEM encoded bundle of certificate authorities used to verify the realm server instead of the system pool
+	CAFile string
+	// TLSConfig is the base TLS configuration that the certificate options are added to
+	TLSConfig *tls.Config
+	// MaxRetries is the number of times an idempotent request is retried after a connection error or a 429, 502, 503 or 504 response
+	MaxRetries int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between retries
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
 }
 
 type HttpClient struct {
 	underlying *http.Client
 	address    *url.URL
+	servers    *serverPool
 	tracer     trace.Tracer
 	propagator propagation.TextMapPropagator
+	token      string
+	headers    map[string]string
+	retry      retryPolicy
 }
 
 func NewHttpClient(c *HttpClientConfig) (*HttpClient, error) {
-	if c.Address == "" {
-		return nil, errors.New("address must not be empty")
-	}
-	u, err := utils.ParseURL(c.Address)
+	servers, err := newServerPool(context.Background(), c)
 	if err != nil {
-		return nil, fmt.Errorf("could not parse address %q: %w", c.Address, err)
+		return nil, err
 	}
 	if c.Timeout <= 0 {
 		c.Timeout = DefaultClientTimeout
 	}
 
+	if c.MaxRetries < 0 {
+		return nil, errors.New("max retries must not be negative")
+	}
+	if c.RetryWaitMin <= 0 {
+		c.RetryWaitMin = DefaultRetryWaitMin
+	}
+	if c.RetryWaitMax < c.RetryWaitMin {
+		c.RetryWaitMax = max(DefaultRetryWaitMax, c.RetryWaitMin)
+	}
+
+	tlsConfig, err := newTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
 	tracer := otel.Tracer("github.com/steviebps/realm")
 
 	return &HttpClient{
-		underlying: &http.Client{Timeout: c.Timeout, Transport: otelhttp.NewTransport(http.DefaultTransport)},
-		address:    u,
+		underlying: &http.Client{Timeout: c.Timeout, Transport: otelhttp.NewTransport(transport)},
+		address:    servers.servers[0].url,
+		servers:    servers,
 		tracer:     tracer,
 		propagator: otel.GetTextMapPropagator(),
+		token:      c.Token,
+		headers:    maps.Clone(c.Headers),
+		retry:      retryPolicy{maxRetries: c.MaxRetries, waitMin: c.RetryWaitMin, waitMax: c.RetryWaitMax},
 	}, nil
 }
 
+// newTLSConfig returns the TLS configuration of the client or nil if the default one should be used
+func newTLSConfig(c *HttpClientConfig) (*tls.Config, error) {
+	if c.TLSConfig == nil && c.CertFile == "" && c.KeyFile == "" && c.CAFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLSConfig != nil {
+		cfg = c.TLSConfig.Clone()
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("certFile must be used in conjunction with keyFile")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle %q: %w", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
 func (c *HttpClient) NewRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
 	logger := logging.Ctx(ctx)
 	logger.DebugCtx(ctx).Str("method", method).Str("path", path).Msg("creating a new request")
-	return http.NewRequestWithContext(ctx, method, c.address.Scheme+"://"+c.address.Host+"/v1/chambers/"+strings.TrimPrefix(path, "/"), body)
+	return c.newRequest(ctx, method, "/v1/chambers/"+strings.TrimPrefix(path, "/"), body)
+}
+
+// newRequest creates a request to the endpoint of the realm server, e.g. /v1/usage
+func (c *HttpClient) newRequest(ctx context.Context, method string, endpoint string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, c.address.Scheme+"://"+c.address.Host+endpoint, body)
 }
 
 func (c *HttpClient) Do(r *http.Request) (*http.Response, error) {
@@ -68,8 +164,18 @@ func (c *HttpClient) Do(r *http.Request) (*http.Response, error) {
 	logger := logging.Ctx(ctx)
 	logger.DebugCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path).Str("host", r.URL.Host).Msg("executing request")
 
+	for k, v := range c.headers {
+		r.Header.Set(k, v)
+	}
+	if c.token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.token)
+	}
 	c.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
-	return c.underlying.Do(r)
+	// the chamber revision is always propagated, even if the global propagator does not include baggage
+	if r.Header.Get("baggage") == "" {
+		propagation.Baggage{}.Inject(ctx, propagation.HeaderCarrier(r.Header))
+	}
+	return c.doWithRetries(ctx, r)
 }
 
 func (c *HttpClient) PerformRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Response, error) {
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	realm "github.com/steviebps/realm/pkg"
+)
+
+func TestRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"rules":{}}}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL, MaxRetries: 2, RetryWaitMin: time.Millisecond, RetryWaitMax: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.GetChamber(context.Background(), "/"); err != nil {
+		t.Fatalf("get should succeed after retrying: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected 3 attempts but made %d", n)
+	}
+
+	calls.Store(0)
+	if err := c.PutChamber(context.Background(), "/", &realm.Chamber{}); err != nil {
+		t.Fatalf("put should succeed after retrying: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("expected 3 attempts but made %d", n)
+	}
+
+	calls.Store(0)
+	if err := c.PatchChamber(context.Background(), "/", nil); !strings.Contains(fmt.Sprint(err), "503") {
+		t.Errorf("patch should fail with the first response but returned: %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("non-idempotent requests should not be retried but made %d attempts", n)
+	}
+}
+
+func TestAuthAndTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Gateway-Key") != "gateway" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errors":["unauthorized"]}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"rules":{}}}`)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  HttpClientConfig
+		wantErr bool
+	}{
+		{"untrusted server", HttpClientConfig{Token: "secret", Headers: map[string]string{"X-Gateway-Key": "gateway"}}, true},
+		{"missing token", HttpClientConfig{CAFile: caFile, Headers: map[string]string{"X-Gateway-Key": "gateway"}}, true},
+		{"authenticated", HttpClientConfig{CAFile: caFile, Token: "secret", Headers: map[string]string{"X-Gateway-Key": "gateway"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Address = srv.URL
+			c, err := NewHttpClient(&tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = c.GetChamber(context.Background(), "/")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error: %v, returned: %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewHttpClient(&HttpClientConfig{Address: srv.URL, CertFile: "cert.pem"}); err == nil {
+		t.Errorf("certFile without keyFile should fail")
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/steviebps/realm/helper/logging"
+)
+
+// retryPolicy decides whether and when a failed request is retried
+type retryPolicy struct {
+	maxRetries int
+	waitMin    time.Duration
+	waitMax    time.Duration
+}
+
+// idempotent reports whether a request with the method can be safely retried
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, "LIST":
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable reports whether the result of a request is worth retrying
+func retryable(ctx context.Context, res *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait returns how long to wait before the retry following attempt, which starts at 0
+func (p retryPolicy) wait(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, p.waitMax)
+		}
+	}
+
+	wait := p.waitMax
+	if attempt < 32 {
+		wait = min(p.waitMin<<attempt, p.waitMax)
+	}
+	// jitter between half and all of the wait so that clients do not retry in lockstep
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// doWithRetries performs the request, retrying idempotent requests according to the retry policy
+func (c *HttpClient) doWithRetries(ctx context.Context, r *http.Request) (*http.Response, error) {
+	replayable := r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+	attempts := 1
+	if idempotent(r.Method) && replayable {
+		attempts += c.retry.maxRetries
+	}
+
+	logger := logging.Ctx(ctx)
+	sent := false
+	for attempt := 0; ; attempt++ {
+		res, err := c.doWithFailover(ctx, r, replayable, &sent)
+		if attempt+1 >= attempts || !retryable(ctx, res, err) {
+			return res, err
+		}
+
+		wait := c.retry.wait(attempt, res)
+		event := logger.DebugCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path).Int("attempt", attempt+1).Dur("wait", wait)
+		if err != nil {
+			event = event.Str("error", err.Error())
+		} else {
+			event = event.Int("status", res.StatusCode)
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		event.Msg("retrying request")
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// doWithFailover sends the request to the healthiest server, failing over to the next one on connection errors or 5xx responses.
+// Requests that are not idempotent only fail over when the connection could not be established, since they were never received
+func (c *HttpClient) doWithFailover(ctx context.Context, r *http.Request, replayable bool, sent *bool) (*http.Response, error) {
+	logger := logging.Ctx(ctx)
+	servers := c.servers.candidates(ctx)
+	for i, s := range servers {
+		if *sent && !replayable {
+			return nil, errors.New("request body cannot be sent again")
+		}
+
+		req := r.Clone(ctx)
+		req.URL.Scheme = s.url.Scheme
+		req.URL.Host = s.url.Host
+		req.Host = s.url.Host
+		if *sent && r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		*sent = true
+
+		res, err := c.underlying.Do(req)
+		if ctx.Err() != nil || (err == nil && res.StatusCode < http.StatusInternalServerError) {
+			if err == nil {
+				c.servers.markSuccess(s)
+			}
+			return res, err
+		}
+
+		c.servers.markFailure(s)
+		if i+1 == len(servers) || !replayable || !(idempotent(r.Method) || dialError(err)) {
+			return res, err
+		}
+
+		event := logger.DebugCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path).Str("server", s.url.Host).Str("next", servers[i+1].url.Host)
+		if err != nil {
+			event = event.Str("error", err.Error())
+		} else {
+			event = event.Int("status", res.StatusCode)
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		event.Msg("failing over to the next server")
+	}
+	return nil, errors.New("no servers available")
+}
+
+// dialError reports whether err happened while establishing a connection, before the request was sent
+func dialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
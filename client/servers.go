@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steviebps/realm/utils"
+)
+
+const (
+	// DefaultSRVScheme is the scheme used to connect to servers discovered with a DNS SRV record
+	DefaultSRVScheme = "https"
+	// srvRefreshInterval is how often a DNS SRV record is resolved again
+	srvRefreshInterval = time.Minute
+	// unhealthyCooldownMin and unhealthyCooldownMax bound how long a failed server is only tried after the healthy ones
+	unhealthyCooldownMin = time.Second
+	unhealthyCooldownMax = time.Minute
+)
+
+// lookupSRV resolves DNS SRV records and is replaced in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// server is a realm server address along with its passive health
+type server struct {
+	url *url.URL
+	// srv is set for servers resolved from the DNS SRV record
+	srv bool
+	// failures is the number of consecutive failed requests
+	failures       int
+	unhealthyUntil time.Time
+}
+
+// serverPool holds the realm servers of a client in order of preference and tracks their health from the results of requests
+type serverPool struct {
+	mu      sync.Mutex
+	servers []*server
+
+	srvName     string
+	srvScheme   string
+	srvResolved time.Time
+}
+
+func newServerPool(ctx context.Context, c *HttpClientConfig) (*serverPool, error) {
+	var addrs []string
+	if c.Address != "" {
+		addrs = append(addrs, c.Address)
+	}
+	addrs = append(addrs, c.Addresses...)
+	if len(addrs) == 0 && c.SRV == "" {
+		return nil, errors.New("address must not be empty")
+	}
+
+	p := &serverPool{srvName: c.SRV, srvScheme: c.SRVScheme}
+	if p.srvScheme == "" {
+		p.srvScheme = DefaultSRVScheme
+	}
+	for _, addr := range addrs {
+		u, err := utils.ParseURL(addr)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse address %q: %w", addr, err)
+		}
+		p.servers = append(p.servers, &server{url: u})
+	}
+
+	if p.srvName != "" {
+		servers, err := p.resolveSRV(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.servers = append(p.servers, servers...)
+		p.srvResolved = time.Now()
+	}
+	return p, nil
+}
+
+// resolveSRV returns the servers of the SRV record in the order of their priority and weight
+func (p *serverPool) resolveSRV(ctx context.Context) ([]*server, error) {
+	_, records, err := lookupSRV(ctx, "", "", p.srvName)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve SRV record %q: %w", p.srvName, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("SRV record %q has no targets", p.srvName)
+	}
+
+	servers := make([]*server, 0, len(records))
+	for _, r := range records {
+		host := net.JoinHostPort(strings.TrimSuffix(r.Target, "."), strconv.Itoa(int(r.Port)))
+		servers = append(servers, &server{url: &url.URL{Scheme: p.srvScheme, Host: host}, srv: true})
+	}
+	return servers, nil
+}
+
+// refreshSRV resolves the SRV record again once the refresh interval passed, keeping the health of known servers.
+// The previous servers are kept if the record cannot be resolved
+func (p *serverPool) refreshSRV(ctx context.Context) {
+	p.mu.Lock()
+	due := p.srvName != "" && time.Since(p.srvResolved) >= srvRefreshInterval
+	if due {
+		p.srvResolved = time.Now()
+	}
+	p.mu.Unlock()
+	if !due {
+		return
+	}
+
+	resolved, err := p.resolveSRV(ctx)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	known := make(map[string]*server, len(p.servers))
+	var static []*server
+	for _, s := range p.servers {
+		known[s.url.String()] = s
+		if !s.srv {
+			static = append(static, s)
+		}
+	}
+	for i, s := range resolved {
+		if prev, ok := known[s.url.String()]; ok {
+			resolved[i] = prev
+		}
+	}
+	p.servers = append(static, resolved...)
+}
+
+// candidates returns the servers in the order they should be tried: healthy servers in order of preference,
+// followed by unhealthy ones starting with the one that becomes healthy the soonest
+func (p *serverPool) candidates(ctx context.Context) []*server {
+	p.refreshSRV(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var healthy, unhealthy []*server
+	for _, s := range p.servers {
+		if now.Before(s.unhealthyUntil) {
+			unhealthy = append(unhealthy, s)
+		} else {
+			healthy = append(healthy, s)
+		}
+	}
+	slices.SortStableFunc(unhealthy, func(a, b *server) int {
+		return a.unhealthyUntil.Compare(b.unhealthyUntil)
+	})
+	return append(healthy, unhealthy...)
+}
+
+func (p *serverPool) markSuccess(s *server) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.failures = 0
+	s.unhealthyUntil = time.Time{}
+}
+
+// markFailure makes the server unhealthy for a cooldown that grows with its consecutive failures
+func (p *serverPool) markFailure(s *server) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cooldown := unhealthyCooldownMax
+	if s.failures < 16 {
+		cooldown = min(unhealthyCooldownMin<<s.failures, unhealthyCooldownMax)
+	}
+	s.failures++
+	s.unhealthyUntil = time.Now().Add(cooldown)
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func newCountingServer(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+		if status < http.StatusBadRequest {
+			fmt.Fprint(w, `{"data":{"rules":{}}}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestFailover(t *testing.T) {
+	failing, failingCalls := newCountingServer(t, http.StatusInternalServerError)
+	healthy, healthyCalls := newCountingServer(t, http.StatusOK)
+
+	// an address that refuses connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + l.Addr().String()
+	l.Close()
+
+	c, err := NewHttpClient(&HttpClientConfig{Address: dead, Addresses: []string{failing.URL, healthy.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetChamber(ctx, "/"); err != nil {
+			t.Fatalf("request %d should fail over to the healthy server: %v", i, err)
+		}
+	}
+	if n := failingCalls.Load(); n != 1 {
+		t.Errorf("unhealthy server should only be tried once but was tried %d times", n)
+	}
+	if n := healthyCalls.Load(); n != 3 {
+		t.Errorf("healthy server should serve every request but served %d", n)
+	}
+
+	// requests that are not idempotent fail over on connection errors but not after they were received
+	c.servers.markFailure(c.servers.servers[2])
+	before := healthyCalls.Load()
+	err = c.PatchChamber(ctx, "/", nil)
+	var re *ResponseError
+	if !errors.As(err, &re) || re.StatusCode != http.StatusInternalServerError {
+		t.Errorf("patch should fail with the response of the failing server but returned: %v", err)
+	}
+	if healthyCalls.Load() != before {
+		t.Errorf("patch should not fail over after a 5xx response")
+	}
+}
+
+func TestSRV(t *testing.T) {
+	healthy, healthyCalls := newCountingServer(t, http.StatusOK)
+	u, _ := url.Parse(healthy.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_realm._tcp.example.com" {
+			return "", nil, fmt.Errorf("unexpected name %q", name)
+		}
+		return "", []*net.SRV{{Target: host + ".", Port: uint16(port)}}, nil
+	}
+	defer func() { lookupSRV = net.DefaultResolver.LookupSRV }()
+
+	c, err := NewHttpClient(&HttpClientConfig{SRV: "_realm._tcp.example.com", SRVScheme: "http"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetChamber(context.Background(), "/"); err != nil {
+		t.Fatal(err)
+	}
+	if healthyCalls.Load() != 1 {
+		t.Errorf("request should be sent to the SRV target")
+	}
+}
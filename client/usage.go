@@ -0,0 +1,45 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	"github.com/steviebps/realm/utils"
+)
+
+// ReportUsage sends the usage report to the realm server
+func (c *HttpClient) ReportUsage(ctx context.Context, report api.UsageReport) error {
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("application", report.Application).Int("chambers", len(report.Chambers)).Msg("reporting usage")
+
+	b, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("could not marshal usage report: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/v1/usage", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var httpRes api.HTTPErrorAndDataResponse
+		if err := utils.ReadInterfaceWith(res.Body, &httpRes); err != nil || len(httpRes.Errors) == 0 {
+			return fmt.Errorf("could not report usage: %s", http.StatusText(res.StatusCode))
+		}
+		return fmt.Errorf("could not report usage: %s", httpRes.Errors)
+	}
+	return nil
+}
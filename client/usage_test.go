@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestReportUsage(t *testing.T) {
+	chamber := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}}}}
+	srv := realmtest.NewServer(t, map[string]*realm.Chamber{"/app/": chamber})
+	c, err := NewHttpClient(&HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	report := api.UsageReport{Application: "checkout", Version: "v1.0.0", Chambers: map[string]map[string]int64{"app": {"enabled": 2}}}
+	if err := c.ReportUsage(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReportUsage(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := c.performAPIRequest(ctx, http.MethodGet, "/v1/usage/app", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records map[string]api.RuleUsage
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	if r := records["enabled"]; r.Count != 4 || r.LastEvaluatedBy != "checkout" || r.LastEvaluatedVersion != "v1.0.0" {
+		t.Errorf("expected the reports to be aggregated but returned %+v", records)
+	}
+
+	err = c.ReportUsage(ctx, api.UsageReport{Chambers: report.Chambers})
+	if err == nil || !strings.Contains(err.Error(), "application must not be empty") {
+		t.Errorf("expected the server's error to be returned but returned: %v", err)
+	}
+}
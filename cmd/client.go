@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"errors"
+
 	"github.com/spf13/cobra"
+	"github.com/steviebps/realm/client"
 )
 
 // clientCmd represents the client command
@@ -15,3 +18,33 @@ func init() {
 	rootCmd.AddCommand(clientCmd)
 	rootCmd.PersistentFlags().StringP("address", "a", "", "address of realm server")
 }
+
+// newHttpClient creates a client from the client section of the config file, using the address flag over the configured address
+func newHttpClient(cmd *cobra.Command) (*client.HttpClient, error) {
+	flags := cmd.Flags()
+	configPath, err := flags.GetString("config")
+	if err != nil {
+		return nil, err
+	}
+
+	var realmConfig RealmConfig
+	if configPath != "" {
+		realmConfig, err = parseConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if addr, _ := flags.GetString("address"); addr != "" {
+		realmConfig.Client.Address = addr
+	}
+	if realmConfig.Client.Address == "" && len(realmConfig.Client.Addresses) == 0 && realmConfig.Client.SRV == "" {
+		return nil, errors.New("must specify an address for the realm server")
+	}
+
+	c, err := realmConfig.Client.httpClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.NewHttpClient(c)
+}
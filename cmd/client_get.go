@@ -1,13 +1,9 @@
 package cmd
 
 import (
-	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/steviebps/realm/api"
-	"github.com/steviebps/realm/client"
 	"github.com/steviebps/realm/helper/logging"
 	"github.com/steviebps/realm/pkg/storage"
 	"github.com/steviebps/realm/utils"
@@ -37,59 +33,19 @@ var clientGet = &cobra.Command{
 		defer span.End()
 		logger := logging.Ctx(ctx)
 
-		var err error
-		flags := cmd.Flags()
-
-		configPath, err := flags.GetString("config")
-		if err != nil {
-			logger.ErrorCtx(ctx).Msg(err.Error())
-			return err
-		}
-
-		var realmConfig RealmConfig
-		if configPath != "" {
-			realmConfig, err = parseConfig(configPath)
-			if err != nil {
-				logger.ErrorCtx(ctx).Msg(err.Error())
-				return err
-			}
-		}
-
-		addr, _ := cmd.Flags().GetString("address")
-		if addr == "" {
-			if realmConfig.Client.Address == "" {
-				err = errors.New("must specify an address for the realm server")
-				logger.ErrorCtx(ctx).Msg(err.Error())
-				return err
-			}
-			addr = realmConfig.Client.Address
-		}
-
-		c, err := client.NewHttpClient(&client.HttpClientConfig{Address: addr})
+		c, err := newHttpClient(cmd)
 		if err != nil {
 			logger.ErrorCtx(ctx).Msg(err.Error())
 			return err
 		}
 
-		res, err := c.PerformRequest(ctx, "GET", strings.TrimPrefix(args[0], "/"), nil)
+		chamber, err := c.GetChamber(ctx, args[0])
 		if err != nil {
-			logger.ErrorCtx(ctx).Msg(err.Error())
-			return err
-		}
-		defer res.Body.Close()
-
-		var httpRes api.HTTPErrorAndDataResponse
-		if err := utils.ReadInterfaceWith(res.Body, &httpRes); err != nil {
-			logger.ErrorCtx(ctx).Str("error", err.Error()).Msg(fmt.Sprintf("could not read response for getting: %q", args[0]))
+			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not get %q: %s", args[0], err.Error()))
 			return err
 		}
 
-		if len(httpRes.Errors) > 0 {
-			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not get %q: %s", args[0], httpRes.Errors))
-			return errors.New(strings.Join(httpRes.Errors, "; "))
-		}
-
-		err = utils.WriteInterfaceWith(cmd.OutOrStdout(), httpRes.Data, true)
+		err = utils.WriteInterfaceWith(cmd.OutOrStdout(), chamber, true)
 		if err != nil {
 			logger.ErrorCtx(ctx).Msg(err.Error())
 			return err
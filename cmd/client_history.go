@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/steviebps/realm/helper/logging"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel"
+)
+
+// clientHistory represents the client history command
+var clientHistory = &cobra.Command{
+	Use:          "history [path]",
+	Short:        "show the history of a chamber",
+	Long:         "history lists the revisions of the chamber at the specified path, most recent first, or prints the chamber at the revision flag",
+	SilenceUsage: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			cmd.SilenceUsage = false
+			return err
+		}
+		if err := storage.ValidatePath(args[0]); err != nil {
+			return fmt.Errorf("invalid path specified: %s, %w", args[0], err)
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tracer := otel.Tracer("github.com/steviebps/realm")
+		ctx, span := tracer.Start(cmd.Context(), "cmd client history")
+		defer span.End()
+		logger := logging.Ctx(ctx)
+
+		c, err := newHttpClient(cmd)
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			return err
+		}
+
+		var out any
+		if revision, _ := cmd.Flags().GetString("revision"); revision != "" {
+			out, err = c.GetChamberAt(ctx, args[0], revision)
+		} else {
+			out, err = c.History(ctx, args[0])
+		}
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not get history of %q: %s", args[0], err.Error()))
+			return err
+		}
+
+		err = utils.WriteInterfaceWith(cmd.OutOrStdout(), out, true)
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			return err
+		}
+		return nil
+	},
+}
+
+// clientRestore represents the client restore command
+var clientRestore = &cobra.Command{
+	Use:          "restore [path] [revision]",
+	Short:        "restore a chamber",
+	Long:         "restore rolls the chamber at the specified path back to one of the revisions listed by history, as a new revision",
+	SilenceUsage: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+			cmd.SilenceUsage = false
+			return err
+		}
+		if err := storage.ValidatePath(args[0]); err != nil {
+			return fmt.Errorf("invalid path specified: %s, %w", args[0], err)
+		}
+
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tracer := otel.Tracer("github.com/steviebps/realm")
+		ctx, span := tracer.Start(cmd.Context(), "cmd client restore")
+		defer span.End()
+		logger := logging.Ctx(ctx)
+
+		c, err := newHttpClient(cmd)
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			return err
+		}
+
+		if err := c.Rollback(ctx, args[0], args[1]); err != nil {
+			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not restore %q to %q: %s", args[0], args[1], err.Error()))
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	clientHistory.Flags().String("revision", "", "revision of the chamber to print")
+	clientCmd.AddCommand(clientHistory)
+	clientCmd.AddCommand(clientRestore)
+}
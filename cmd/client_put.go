@@ -1,19 +1,12 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/steviebps/realm/api"
-	"github.com/steviebps/realm/client"
 	"github.com/steviebps/realm/helper/logging"
 	realm "github.com/steviebps/realm/pkg"
 	"github.com/steviebps/realm/pkg/storage"
-	"github.com/steviebps/realm/utils"
 	"go.opentelemetry.io/otel"
 )
 
@@ -40,69 +33,18 @@ var clientPut = &cobra.Command{
 		defer span.End()
 		logger := logging.Ctx(ctx)
 
-		var err error
-		flags := cmd.Flags()
-
-		configPath, err := flags.GetString("config")
-		if err != nil {
-			logger.ErrorCtx(ctx).Msg(err.Error())
-			return err
-		}
-
-		var realmConfig RealmConfig
-		if configPath != "" {
-			realmConfig, err = parseConfig(configPath)
-			if err != nil {
-				logger.ErrorCtx(ctx).Msg(err.Error())
-				return err
-			}
-		}
-
-		addr, _ := cmd.Flags().GetString("address")
-		if addr == "" {
-			if realmConfig.Client.Address == "" {
-				err = errors.New("must specify an address for the realm server")
-				logger.ErrorCtx(ctx).Msg(err.Error())
-				return err
-			}
-			addr = realmConfig.Client.Address
-		}
-
-		c, err := client.NewHttpClient(&client.HttpClientConfig{Address: addr})
-		if err != nil {
-			logger.ErrorCtx(ctx).Msg(err.Error())
-			return err
-		}
-
-		emptyChamber, err := json.Marshal(&realm.Chamber{Rules: map[string]*realm.OverrideableRule{}})
-		if err != nil {
-			logger.ErrorCtx(ctx).Str("error", err.Error()).Msg(fmt.Sprintf("could not marshal empty chamber for putting: %q", args[0]))
-			return err
-		}
-
-		res, err := c.PerformRequest(ctx, "POST", strings.TrimPrefix(args[0], "/"), bytes.NewReader(emptyChamber))
+		c, err := newHttpClient(cmd)
 		if err != nil {
 			logger.ErrorCtx(ctx).Msg(err.Error())
 			return err
 		}
-		defer res.Body.Close()
 
-		var httpRes api.HTTPErrorAndDataResponse
-		if err := utils.ReadInterfaceWith(res.Body, &httpRes); err != nil {
-			logger.ErrorCtx(ctx).Str("error", err.Error()).Msg(fmt.Sprintf("could not read response for putting: %q", args[0]))
+		if err := c.PutChamber(ctx, args[0], &realm.Chamber{Rules: map[string]*realm.OverrideableRule{}}); err != nil {
+			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not put %q: %s", args[0], err.Error()))
 			return err
 		}
 
-		if len(httpRes.Errors) > 0 {
-			logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not put %q: %s", args[0], httpRes.Errors))
-			return err
-		}
-
-		err = utils.WriteInterfaceWith(cmd.OutOrStdout(), httpRes.Data, true)
-		if err != nil {
-			logger.ErrorCtx(ctx).Msg(err.Error())
-			return err
-		}
+		logger.InfoCtx(ctx).Msg(fmt.Sprintf("successfully put %q", args[0]))
 		return nil
 	},
 }
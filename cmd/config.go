@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
 	"strconv"
 	"time"
 
+	"github.com/steviebps/realm/client"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/audit"
+	"github.com/steviebps/realm/pkg/storage"
 	"github.com/steviebps/realm/utils"
 )
 
@@ -27,10 +35,226 @@ type ServerConfig struct {
 	KeyFile        string            `json:"keyFile"`
 	LogLevel       string            `json:"logLevel"`
 	Inheritable    bool              `json:"inheritable"`
+	// UsageStorageType is the storage type rule usage is persisted to. Usage is kept in memory when it is empty
+	UsageStorageType    string            `json:"usageStorage,omitempty"`
+	UsageStorageOptions map[string]string `json:"usageOptions,omitempty"`
+	// HistoryStorageType is the storage type the revisions of chambers are persisted to. Revisions are kept in memory when it is empty
+	HistoryStorageType    string            `json:"historyStorage,omitempty"`
+	HistoryStorageOptions map[string]string `json:"historyOptions,omitempty"`
+	// HistoryRetention is the number of revisions kept per chamber
+	HistoryRetention int `json:"historyRetention,omitempty"`
+	// Auth configures the tokens that mutating requests must be authenticated with
+	Auth AuthConfig `json:"auth,omitempty"`
+	// Audit are the sinks that changes to chambers are recorded to. The first sink that can be queried serves /v1/audit
+	Audit []AuditSinkConfig `json:"audit,omitempty"`
+}
+
+type AuditSinkConfig struct {
+	// Type is one of file, storage or stdout
+	Type string `json:"type"`
+	// Path is the JSON Lines file of the file sink
+	Path string `json:"path,omitempty"`
+	// StorageType and StorageOptions configure the storage of the storage sink
+	StorageType    string            `json:"storage,omitempty"`
+	StorageOptions map[string]string `json:"options,omitempty"`
+}
+
+// auditSinks creates the audit sinks of the config and returns a function that closes them
+func auditSinks(configs []AuditSinkConfig) (audit.Sink, func(ctx context.Context), error) {
+	var sinks audit.MultiSink
+	var closers []func(ctx context.Context)
+	closeAll := func(ctx context.Context) {
+		for _, c := range closers {
+			c(ctx)
+		}
+	}
+
+	for _, ac := range configs {
+		switch ac.Type {
+		case "stdout":
+			sinks = append(sinks, audit.NewWriterSink(os.Stdout))
+		case "file":
+			fs, err := audit.NewFileSink(ac.Path)
+			if err != nil {
+				closeAll(context.Background())
+				return nil, nil, err
+			}
+			sinks = append(sinks, fs)
+			closers = append(closers, func(context.Context) { fs.Close() })
+		case "storage":
+			creator, exists := storage.SourcableStorageOptions[ac.StorageType]
+			if !exists {
+				closeAll(context.Background())
+				return nil, nil, fmt.Errorf("audit storage type %q does not exist", ac.StorageType)
+			}
+			stg, err := creator(ac.StorageOptions)
+			if err != nil {
+				closeAll(context.Background())
+				return nil, nil, err
+			}
+			sinks = append(sinks, audit.NewStorageSink(stg))
+			closers = append(closers, func(ctx context.Context) { stg.Close(ctx) })
+		default:
+			closeAll(context.Background())
+			return nil, nil, fmt.Errorf("audit sink type %q does not exist", ac.Type)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, closeAll, nil
+	}
+	return sinks, closeAll, nil
+}
+
+type AuthConfig struct {
+	// Tokens are static API tokens, stored as the hash printed by "realm token hash"
+	Tokens []TokenConfig `json:"tokens,omitempty"`
+	// HMACKeys verify the tokens created by "realm token sign". Secrets are read from a file or an environment variable so that they are not stored in the config
+	HMACKeys []HMACKeyConfig `json:"hmacKeys,omitempty"`
+	// JWT verifies bearer tokens issued by an OIDC provider
+	JWT *JWTConfig `json:"jwt,omitempty"`
+	// Policies restrict the chamber paths that callers can read, list, write and delete. Identities are granted policies by name
+	Policies          []realmhttp.Policy  `json:"policies,omitempty"`
+	IdentityPolicies  map[string][]string `json:"identityPolicies,omitempty"`
+	GroupPolicies     map[string][]string `json:"groupPolicies,omitempty"`
+	AnonymousPolicies []string            `json:"anonymousPolicies,omitempty"`
+}
+
+type TokenConfig struct {
+	Name     string   `json:"name"`
+	Hash     string   `json:"hash"`
+	Policies []string `json:"policies,omitempty"`
+}
+
+type JWTConfig struct {
+	JWKSFile      string   `json:"jwksFile,omitempty"`
+	JWKSURL       string   `json:"jwksUrl,omitempty"`
+	Issuer        string   `json:"issuer"`
+	Audiences     []string `json:"audiences"`
+	IdentityClaim string   `json:"identityClaim,omitempty"`
+	GroupsClaim   string   `json:"groupsClaim,omitempty"`
+	Algorithms    []string `json:"algorithms,omitempty"`
+	// RefreshInterval is a duration such as "5m"
+	RefreshInterval string `json:"refreshInterval,omitempty"`
+}
+
+type HMACKeyConfig struct {
+	ID         string `json:"id"`
+	SecretFile string `json:"secretFile,omitempty"`
+	SecretEnv  string `json:"secretEnv,omitempty"`
+}
+
+// secret reads the secret of the key from its file or environment variable
+func (kc HMACKeyConfig) secret() ([]byte, error) {
+	switch {
+	case kc.SecretFile != "" && kc.SecretEnv != "":
+		return nil, fmt.Errorf("HMAC key %q must have either a secretFile or a secretEnv", kc.ID)
+	case kc.SecretFile != "":
+		b, err := os.ReadFile(kc.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read secret of HMAC key %q: %w", kc.ID, err)
+		}
+		return bytes.TrimSpace(b), nil
+	case kc.SecretEnv != "":
+		v := os.Getenv(kc.SecretEnv)
+		if v == "" {
+			return nil, fmt.Errorf("environment variable %s of HMAC key %q is empty", kc.SecretEnv, kc.ID)
+		}
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("HMAC key %q must have a secretFile or a secretEnv", kc.ID)
+}
+
+// authConfig converts the auth section of the config into the auth configuration of the http handler
+func (ac AuthConfig) authConfig() (realmhttp.AuthConfig, error) {
+	c := realmhttp.AuthConfig{Policies: ac.Policies, IdentityPolicies: ac.IdentityPolicies, GroupPolicies: ac.GroupPolicies, AnonymousPolicies: ac.AnonymousPolicies}
+	for _, t := range ac.Tokens {
+		c.Tokens = append(c.Tokens, realmhttp.StaticToken{Name: t.Name, Hash: t.Hash, Policies: t.Policies})
+	}
+	for _, k := range ac.HMACKeys {
+		secret, err := k.secret()
+		if err != nil {
+			return c, err
+		}
+		c.HMACKeys = append(c.HMACKeys, realmhttp.HMACKey{ID: k.ID, Secret: secret})
+	}
+	if jc := ac.JWT; jc != nil {
+		c.JWT = &realmhttp.JWTConfig{
+			JWKSFile:      jc.JWKSFile,
+			JWKSURL:       jc.JWKSURL,
+			Issuer:        jc.Issuer,
+			Audiences:     jc.Audiences,
+			IdentityClaim: jc.IdentityClaim,
+			GroupsClaim:   jc.GroupsClaim,
+			Algorithms:    jc.Algorithms,
+		}
+		if jc.RefreshInterval != "" {
+			interval, err := time.ParseDuration(jc.RefreshInterval)
+			if err != nil {
+				return c, fmt.Errorf("invalid jwt refreshInterval %q: %w", jc.RefreshInterval, err)
+			}
+			c.JWT.RefreshInterval = interval
+		}
+	}
+	return c, nil
 }
 
 type ClientConfig struct {
 	Address string `json:"address"`
+	// Addresses are additional servers that requests fail over to and SRV is a DNS SRV record name to discover servers with
+	Addresses []string `json:"addresses,omitempty"`
+	SRV       string   `json:"srv,omitempty"`
+	SRVScheme string   `json:"srvScheme,omitempty"`
+	// Token is sent as a bearer token with every request. The REALM_TOKEN environment variable is used when it is empty
+	Token    string            `json:"token,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	CertFile string            `json:"certFile,omitempty"`
+	KeyFile  string            `json:"keyFile,omitempty"`
+	CAFile   string            `json:"caFile,omitempty"`
+	// Timeout, RetryWaitMin and RetryWaitMax are durations such as "500ms" or "10s"
+	Timeout      string `json:"timeout,omitempty"`
+	MaxRetries   int    `json:"maxRetries,omitempty"`
+	RetryWaitMin string `json:"retryWaitMin,omitempty"`
+	RetryWaitMax string `json:"retryWaitMax,omitempty"`
+}
+
+// httpClientConfig converts the client section of the config into the configuration of an http client
+func (cc ClientConfig) httpClientConfig() (*client.HttpClientConfig, error) {
+	c := &client.HttpClientConfig{
+		Address:    cc.Address,
+		Addresses:  cc.Addresses,
+		SRV:        cc.SRV,
+		SRVScheme:  cc.SRVScheme,
+		Token:      cc.Token,
+		Headers:    cc.Headers,
+		CertFile:   cc.CertFile,
+		KeyFile:    cc.KeyFile,
+		CAFile:     cc.CAFile,
+		MaxRetries: cc.MaxRetries,
+	}
+	if c.Token == "" {
+		c.Token = os.Getenv("REALM_TOKEN")
+	}
+
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"timeout", cc.Timeout, &c.Timeout},
+		{"retryWaitMin", cc.RetryWaitMin, &c.RetryWaitMin},
+		{"retryWaitMax", cc.RetryWaitMax, &c.RetryWaitMax},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client %s %q: %w", d.name, d.value, err)
+		}
+		*d.dest = v
+	}
+	return c, nil
 }
 
 func parseConfig(path string) (RealmConfig, error) {
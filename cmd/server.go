@@ -132,7 +132,57 @@ var serverCmd = &cobra.Command{
 			}
 		}
 
-		handler, err := realmhttp.NewHandler(ctx, realmhttp.HandlerConfig{Storage: stg, RequestTimeout: realmhttp.DefaultHandlerTimeout})
+		var usageStg storage.Storage
+		if serverConfig.UsageStorageType != "" {
+			usageCreator, exists := storage.SourcableStorageOptions[serverConfig.UsageStorageType]
+			if !exists {
+				logger.ErrorCtx(ctx).Msg(fmt.Sprintf("usage storage type %q does not exist", serverConfig.UsageStorageType))
+				os.Exit(1)
+			}
+			usageStg, err = usageCreator(serverConfig.UsageStorageOptions)
+			if err != nil {
+				logger.ErrorCtx(ctx).Msg(err.Error())
+				os.Exit(1)
+			}
+			defer usageStg.Close(ctx)
+		} else {
+			logger.WarnCtx(ctx).Msg("no usage storage is configured, rule usage is kept in memory and lost on restart")
+		}
+
+		var historyStg storage.Storage
+		if serverConfig.HistoryStorageType != "" {
+			historyCreator, exists := storage.SourcableStorageOptions[serverConfig.HistoryStorageType]
+			if !exists {
+				logger.ErrorCtx(ctx).Msg(fmt.Sprintf("history storage type %q does not exist", serverConfig.HistoryStorageType))
+				os.Exit(1)
+			}
+			historyStg, err = historyCreator(serverConfig.HistoryStorageOptions)
+			if err != nil {
+				logger.ErrorCtx(ctx).Msg(err.Error())
+				os.Exit(1)
+			}
+			defer historyStg.Close(ctx)
+		} else {
+			logger.WarnCtx(ctx).Msg("no history storage is configured, the revisions of chambers are kept in memory and lost on restart")
+		}
+
+		authConfig, err := serverConfig.Auth.authConfig()
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			os.Exit(1)
+		}
+		if len(authConfig.Tokens) == 0 && len(authConfig.HMACKeys) == 0 && authConfig.JWT == nil {
+			logger.WarnCtx(ctx).Msg("no tokens are configured, mutating requests are not authenticated")
+		}
+
+		auditSink, closeAudit, err := auditSinks(serverConfig.Audit)
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			os.Exit(1)
+		}
+		defer closeAudit(ctx)
+
+		handler, err := realmhttp.NewHandler(ctx, realmhttp.HandlerConfig{Storage: stg, RequestTimeout: realmhttp.DefaultHandlerTimeout, UsageStorage: usageStg, HistoryStorage: historyStg, HistoryRetention: serverConfig.HistoryRetention, Auth: authConfig, AuditSink: auditSink, Version: Version})
 		if err != nil {
 			logger.ErrorCtx(ctx).Msg(err.Error())
 			os.Exit(1)
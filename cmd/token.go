@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steviebps/realm/helper/logging"
+	realmhttp "github.com/steviebps/realm/http"
+)
+
+// tokenCmd represents the token command
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens",
+	Long:  "Commands for creating the API tokens that the realm server authenticates requests with",
+}
+
+// tokenHash represents the token hash command
+var tokenHash = &cobra.Command{
+	Use:          "hash [token]",
+	Short:        "hash a static token",
+	Long:         "hash prints the hash of a static token to add to the auth tokens of the server config. The token is read from stdin when it is not specified",
+	SilenceUsage: true,
+	Args:         cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var token string
+		if len(args) > 0 {
+			token = args[0]
+		} else {
+			line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("could not read token from stdin: %w", err)
+			}
+			token = strings.TrimSpace(line)
+		}
+		if token == "" {
+			return errors.New("token must not be empty")
+		}
+
+		fmt.Fprintln(cmd.OutOrStdout(), realmhttp.HashToken(token))
+		return nil
+	},
+}
+
+// tokenSign represents the token sign command
+var tokenSign = &cobra.Command{
+	Use:          "sign [subject]",
+	Short:        "sign a token",
+	Long:         "sign creates a token for the subject that is signed with one of the HMAC keys of the server config",
+	SilenceUsage: true,
+	Args:         cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		logger := logging.Ctx(ctx)
+		flags := cmd.Flags()
+		keyID, _ := flags.GetString("key")
+		ttl, _ := flags.GetDuration("ttl")
+		if ttl <= 0 {
+			return errors.New("ttl must be positive")
+		}
+
+		configPath, _ := flags.GetString("config")
+		if configPath == "" {
+			return errors.New("config must be specified")
+		}
+		realmConfig, err := parseConfig(configPath)
+		if err != nil {
+			logger.ErrorCtx(ctx).Msg(err.Error())
+			return err
+		}
+
+		for _, k := range realmConfig.Server.Auth.HMACKeys {
+			if k.ID != keyID {
+				continue
+			}
+			secret, err := k.secret()
+			if err != nil {
+				logger.ErrorCtx(ctx).Msg(err.Error())
+				return err
+			}
+			token, err := realmhttp.SignToken(k.ID, secret, args[0], time.Now().Add(ttl))
+			if err != nil {
+				logger.ErrorCtx(ctx).Msg(err.Error())
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), token)
+			return nil
+		}
+		return fmt.Errorf("HMAC key %q is not configured", keyID)
+	},
+}
+
+func init() {
+	tokenSign.Flags().String("key", "", "id of the HMAC key to sign the token with")
+	tokenSign.MarkFlagRequired("key")
+	tokenSign.Flags().Duration("ttl", 24*time.Hour, "time until the token expires")
+	tokenCmd.AddCommand(tokenHash)
+	tokenCmd.AddCommand(tokenSign)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -37,7 +37,9 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	err = rlm.Start()
+	startCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	err = rlm.Start(startCtx)
+	cancel()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -77,8 +79,12 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	rlm.Stop()
-	if err := server.Shutdown(bootCtx); err != nil {
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := rlm.Stop(shutdownCtx); err != nil {
 		log.Fatal(err.Error())
 	}
 }
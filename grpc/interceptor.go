@@ -0,0 +1,111 @@
+// Package grpc provides gRPC server interceptors equivalent to realmhttp.RealmHandler
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	realm "github.com/steviebps/realm/pkg"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Extractor reads evaluation attributes from the incoming metadata of a call into its evaluation context.
+// Extractors are applied in order, so later extractors take precedence over earlier ones
+type Extractor func(md metadata.MD, ec *realm.EvaluationContext)
+
+// VersionFromMetadata extracts the application version from the metadata key
+func VersionFromMetadata(key string) Extractor {
+	return func(md metadata.MD, ec *realm.EvaluationContext) {
+		if v := firstValue(md, key); v != "" {
+			ec.Version = v
+		}
+	}
+}
+
+// AttributeFromMetadata extracts the evaluation attribute from the metadata key
+func AttributeFromMetadata(attribute string, key string) Extractor {
+	return func(md metadata.MD, ec *realm.EvaluationContext) {
+		v := firstValue(md, key)
+		if v == "" {
+			return
+		}
+		if ec.Attributes == nil {
+			ec.Attributes = make(map[string]string)
+		}
+		ec.Attributes[attribute] = v
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if vs := md.Get(strings.ToLower(key)); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// UnaryServerInterceptor associates the current chamber of rlm with every unary call so that it is consistent for the whole call.
+// The extractors read the evaluation context of each call from its metadata, e.g. the caller's application version.
+// The chamber revision the caller evaluated is read from the call's OpenTelemetry baggage, see realm.WithRevisionPinning
+func UnaryServerInterceptor(rlm *realm.Realm, extractors ...Extractor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(newContext(ctx, rlm, extractors), req)
+	}
+}
+
+// StreamServerInterceptor associates the current chamber of rlm with every stream so that it is consistent for the whole stream.
+// The extractors read the evaluation context of each stream from its metadata, e.g. the caller's application version
+func StreamServerInterceptor(rlm *realm.Realm, extractors ...Extractor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: newContext(ss.Context(), rlm, extractors)})
+	}
+}
+
+func newContext(ctx context.Context, rlm *realm.Realm, extractors []Extractor) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if baggage.FromContext(ctx).Member(realm.RevisionBaggageKey).Key() == "" {
+		ctx = propagation.Baggage{}.Extract(ctx, metadataCarrier(md))
+	}
+
+	ctx = rlm.NewContext(ctx)
+	if len(extractors) == 0 {
+		return ctx
+	}
+
+	var ec realm.EvaluationContext
+	for _, extract := range extractors {
+		extract(md, &ec)
+	}
+	return realm.NewEvaluationContext(ctx, ec)
+}
+
+// metadataCarrier reads propagated values such as baggage from incoming metadata
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	return firstValue(metadata.MD(mc), key)
+}
+
+func (mc metadataCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc))
+	for k := range mc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// serverStream overrides the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,75 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	realmgrpc "github.com/steviebps/realm/grpc"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	fake := realmtest.New(t, &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"message": {
+			Rule: &realm.Rule{Type: "string", Value: "default"},
+			Overrides: []*realm.Override{
+				{Rule: &realm.Rule{Type: "string", Value: "v2"}, MinimumVersion: "v2.0.0", MaximumVersion: "v2.9.9"},
+			},
+		},
+	}}, realm.WithVersion("v1.0.0"))
+	rlm := fake.Realm()
+	extractors := []realmgrpc.Extractor{realmgrpc.VersionFromMetadata("x-app-version"), realmgrpc.AttributeFromMetadata("tenant", "X-Tenant")}
+
+	evaluate := func(ctx context.Context) string {
+		message, _ := rlm.String(ctx, "message", "")
+		ec, _ := realm.EvaluationContextFrom(ctx)
+		return message + " " + ec.Attributes["tenant"]
+	}
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		expected string
+	}{
+		{"realm version", metadata.MD{}, "default "},
+		{"version and attribute from metadata", metadata.Pairs("x-app-version", "v2.1.0", "x-tenant", "acme"), "v2 acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			unary := realmgrpc.UnaryServerInterceptor(rlm, extractors...)
+			res, err := unary(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				return evaluate(ctx), nil
+			})
+			if err != nil || res != tt.expected {
+				t.Errorf("unary: expected %q but returned %q with error: %v", tt.expected, res, err)
+			}
+
+			var got string
+			stream := realmgrpc.StreamServerInterceptor(rlm, extractors...)
+			err = stream(nil, &testStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv any, ss grpc.ServerStream) error {
+				got = evaluate(ss.Context())
+				if _, ok := ss.Context().Value(realm.RequestContextKey).(*realm.ChamberEntry); !ok {
+					t.Errorf("stream context should hold a chamber snapshot")
+				}
+				return nil
+			})
+			if err != nil || got != tt.expected {
+				t.Errorf("stream: expected %q but returned %q with error: %v", tt.expected, got, err)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package logging
 import (
 	"context"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,6 +19,8 @@ var (
 	loggerContextKey = &contextKey{"realm-logger"}
 	// nopLogger is a no-op logger used when no logger is found in the context
 	nopLogger = &TracedLogger{Logger: zerolog.Nop()}
+	// setTimeFormat sets the global zerolog time format once so that creating loggers does not race with logging
+	setTimeFormat sync.Once
 )
 
 // TracedLogger wraps zerolog.Logger with context-aware methods
@@ -27,7 +30,9 @@ type TracedLogger struct {
 
 // NewTracedLogger creates a new traced logger
 func NewTracedLogger() *TracedLogger {
-	zerolog.TimeFieldFormat = time.RFC3339Nano
+	setTimeFormat.Do(func() {
+		zerolog.TimeFieldFormat = time.RFC3339Nano
+	})
 
 	consoleWriter := zerolog.NewConsoleWriter()
 	multi := zerolog.MultiLevelWriter(consoleWriter, os.Stderr)
@@ -25,10 +25,28 @@ type AgentRequest struct {
 	ID        string
 	Operation Operation
 	Path      string
+	// RuleKey is the key of the rule the request operates on within the chamber at Path, it is empty for chamber requests
+	RuleKey string
 }
 
+// RequestIDHeader carries the ID of a request. The ID is generated when the caller does not supply one and is returned in the response
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDSize limits the size of request IDs supplied by callers
+const maxRequestIDSize = 128
+
+// rulesEndpoint is the endpoint of single rule requests, e.g. /v1/rules/app?key=enabled.
+// The key is a query parameter so that every chamber path, including /app/rules/beta/, remains a chamber request
+const rulesEndpoint = "/v1/rules/"
+
 func buildAgentRequest(req *http.Request) *AgentRequest {
-	p, _ := url.PathUnescape(strings.TrimPrefix(req.URL.Path, "/v1/chambers"))
+	prefix := "/v1/chambers"
+	var ruleKey string
+	if strings.HasPrefix(req.URL.Path, rulesEndpoint) {
+		prefix = strings.TrimSuffix(rulesEndpoint, "/")
+		ruleKey = req.URL.Query().Get("key")
+	}
+	p, _ := url.PathUnescape(strings.TrimPrefix(req.URL.Path, prefix))
 	var op Operation
 
 	switch req.Method {
@@ -41,7 +59,7 @@ func buildAgentRequest(req *http.Request) *AgentRequest {
 				op = ListOperation
 			}
 		}
-	case http.MethodPost:
+	case http.MethodPost, http.MethodPut:
 		op = PutOperation
 	case http.MethodPatch:
 		op = PatchOperation
@@ -55,8 +73,18 @@ func buildAgentRequest(req *http.Request) *AgentRequest {
 
 	return &AgentRequest{
 		Request:   req,
-		ID:        uuid.New().String(),
+		ID:        requestID(req),
 		Operation: op,
 		Path:      Path,
+		RuleKey:   ruleKey,
+	}
+}
+
+// requestID returns the ID supplied by the caller of r or a new one
+func requestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDSize {
+		id = uuid.New().String()
 	}
+	return id
 }
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/audit"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ChangeMessageHeader is the header callers explain the reason for a change of a chamber with, it is recorded in the audit log
+const ChangeMessageHeader = "X-Realm-Change-Message"
+
+// maxChangeMessageSize limits the size of the change message recorded in the audit log
+const maxChangeMessageSize = 1024
+
+// auditor records the changes made by chamber requests to the audit sink
+type auditor struct {
+	sink audit.Sink
+	now  func() time.Time
+}
+
+func newAuditor(sink audit.Sink) *auditor {
+	if sink == nil {
+		return nil
+	}
+	return &auditor{sink: sink, now: time.Now}
+}
+
+// record writes the change of the chamber at req.Path from before to after, which are nil when the chamber does not exist.
+// The change has already been applied so a failure to record it is only logged
+func (a *auditor) record(ctx context.Context, req *AgentRequest, before *storage.StorageEntry, after *storage.StorageEntry) {
+	if a == nil {
+		return
+	}
+	logger := logging.Ctx(ctx)
+
+	event := api.AuditEvent{
+		ID:        uuid.New().String(),
+		Time:      a.now().UTC(),
+		RequestID: req.ID,
+		Operation: string(req.Operation),
+		Path:      req.Path,
+		RuleKey:   req.RuleKey,
+		Message:   changeMessage(req.Request),
+	}
+	if id, ok := IdentityFrom(ctx); ok {
+		event.Identity = id.Name
+		event.AuthMethod = string(id.Method)
+	}
+
+	var chambers [2]*realm.Chamber
+	for i, entry := range []*storage.StorageEntry{before, after} {
+		if entry == nil {
+			continue
+		}
+		c, err := unmarshalChamber(req.Path, entry)
+		if err != nil {
+			logger.ErrorCtx(ctx).Str("path", req.Path).Msg(err.Error())
+			continue
+		}
+		chambers[i] = c
+	}
+	if chambers[1] != nil {
+		event.Revision = chambers[1].Revision()
+	}
+	changes, err := audit.Diff(chambers[0], chambers[1])
+	if err != nil {
+		logger.ErrorCtx(ctx).Str("path", req.Path).Msg(err.Error())
+	}
+	event.Changes = changes
+
+	if err := a.sink.Write(ctx, event); err != nil {
+		logger.ErrorCtx(ctx).Str("path", req.Path).Str("request_id", req.ID).Msgf("could not write audit event: %s", err.Error())
+	}
+}
+
+func changeMessage(r *http.Request) string {
+	msg := r.Header.Get(ChangeMessageHeader)
+	if len(msg) > maxChangeMessageSize {
+		msg = msg[:maxChangeMessageSize]
+	}
+	return msg
+}
+
+// handleAudit returns the audit events matching the path, since, until, recursive and limit query parameters on GET /v1/audit, most recent first.
+// Only the events of the chambers the caller can read are returned
+func handleAudit(aud *auditor, authz *authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.Ctx(ctx)
+		errorLog := logger.ErrorCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path)
+		span := trace.SpanFromContext(ctx)
+
+		if r.Method != http.MethodGet {
+			span.SetStatus(codes.Error, "method not allowed")
+			handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+			return
+		}
+
+		var querier audit.Querier
+		if aud != nil {
+			querier, _ = aud.sink.(audit.Querier)
+		}
+		if querier == nil {
+			handleError(ctx, w, http.StatusNotImplemented, createResponseWithErrors(nil, []string{audit.ErrNotQueryable.Error()}))
+			return
+		}
+
+		q, err := parseAuditQuery(r)
+		if err != nil {
+			handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+		p := utils.EnsureTrailingSlash("/" + strings.TrimPrefix(q.Path, "/"))
+		span.SetAttributes(attribute.String("realm.server.logicalPath", p))
+		if !authz.allowed(ctx, p, ReadCapability) {
+			msg := fmt.Sprintf("%s is not permitted on %s", ReadCapability, p)
+			span.SetStatus(codes.Error, msg)
+			handleUnauthorized(ctx, w, msg)
+			return
+		}
+
+		// filtered by the sink so that the events the caller cannot read do not count towards the limit
+		q.Filter = func(e api.AuditEvent) bool {
+			return authz.allowed(ctx, e.Path, ReadCapability)
+		}
+		events, err := querier.Query(ctx, q)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			errorLog.Msg(err.Error())
+			status := http.StatusInternalServerError
+			if errors.Is(err, audit.ErrNotQueryable) {
+				status = http.StatusNotImplemented
+			}
+			handleError(ctx, w, status, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+
+		raw, err := json.Marshal(events)
+		if err != nil {
+			handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+		handleOk(w, createResponseWithErrors(raw, nil))
+	})
+}
+
+func parseAuditQuery(r *http.Request) (audit.Query, error) {
+	values := r.URL.Query()
+	q := audit.Query{Path: values.Get("path")}
+	if err := storage.ValidatePath(q.Path); err != nil {
+		return q, fmt.Errorf("invalid path %q: %w", q.Path, err)
+	}
+
+	times := []struct {
+		name string
+		dest *time.Time
+	}{
+		{"since", &q.Since},
+		{"until", &q.Until},
+	}
+	for _, t := range times {
+		s := values.Get(t.name)
+		if s == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return q, fmt.Errorf("invalid %s %q: must be an RFC 3339 timestamp", t.name, s)
+		}
+		*t.dest = v
+	}
+
+	if s := values.Get("recursive"); s != "" {
+		recursive, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid recursive %q: %w", s, err)
+		}
+		q.Recursive = recursive
+	}
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 {
+			return q, fmt.Errorf("invalid limit %q: must be a positive integer", s)
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
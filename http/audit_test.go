@@ -0,0 +1,158 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/audit"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestAudit(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditStg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage:   stg,
+		AuditSink: audit.NewStorageSink(auditStg),
+		Auth: realmhttp.AuthConfig{
+			Tokens: []realmhttp.StaticToken{{Name: "alice", Hash: realmhttp.HashToken("alice-token")}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer alice-token")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	steps := []struct {
+		method  string
+		target  string
+		body    string
+		headers map[string]string
+	}{
+		{http.MethodPost, "/v1/chambers/app", `{"rules":{"enabled":{"type":"boolean","value":true}}}`, map[string]string{realmhttp.RequestIDHeader: "req-1"}},
+		{http.MethodPut, "/v1/rules/app?key=enabled", `{"type":"boolean","value":false}`, map[string]string{realmhttp.ChangeMessageHeader: "incident 42"}},
+		{http.MethodPost, "/v1/chambers/other", `{"rules":{}}`, nil},
+		{http.MethodDelete, "/v1/chambers/app", "", nil},
+	}
+	for _, s := range steps {
+		if rec := do(s.method, s.target, s.body, s.headers); rec.Code >= 300 {
+			t.Fatalf("%s %s returned %d: %s", s.method, s.target, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := do(http.MethodGet, "/v1/audit?path=/app/", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d but returned %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var res api.HTTPErrorAndDataResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var events []api.AuditEvent
+	if err := json.Unmarshal(res.Data, &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events but returned %d: %+v", len(events), events)
+	}
+
+	deleted, changed, created := events[0], events[1], events[2]
+	if deleted.Operation != "delete" || deleted.Revision != "" || len(deleted.Changes) != 1 || deleted.Changes[0].Change != api.RuleRemoved {
+		t.Errorf("unexpected delete event: %+v", deleted)
+	}
+	if changed.RuleKey != "enabled" || changed.Message != "incident 42" || len(changed.Changes) != 1 || changed.Changes[0].Change != api.RuleModified {
+		t.Errorf("unexpected rule event: %+v", changed)
+	}
+	if created.RequestID != "req-1" || created.Identity != "alice" || created.Path != "/app/" || created.Revision == "" {
+		t.Errorf("unexpected create event: %+v", created)
+	}
+
+	if rec := do(http.MethodGet, "/v1/audit?since=yesterday", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid time but returned %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestAuditReadableEvents(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditStg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := audit.NewStorageSink(auditStg)
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage:   stg,
+		AuditSink: sink,
+		Auth: realmhttp.AuthConfig{
+			Tokens:   []realmhttp.StaticToken{{Name: "payments-ci", Hash: realmhttp.HashToken("payments-token"), Policies: []string{"payments"}}},
+			Policies: testPolicies,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the newest events are on a chamber the caller cannot read
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []api.AuditEvent{
+		{ID: "1", Time: start, Path: "/payments/eu/", Operation: "put"},
+		{ID: "2", Time: start.Add(time.Minute), Path: "/payments/", Operation: "put"},
+		{ID: "3", Time: start.Add(2 * time.Minute), Path: "/payments/locked/", Operation: "put"},
+		{ID: "4", Time: start.Add(3 * time.Minute), Path: "/payments/locked/", Operation: "patch"},
+	}
+	for _, e := range events {
+		if err := sink.Write(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/audit?path=/payments/&recursive=true&limit=2", nil)
+	req.Header.Set("Authorization", "Bearer payments-token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d but returned %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var res api.HTTPErrorAndDataResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var found []api.AuditEvent
+	if err := json.Unmarshal(res.Data, &found); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, e := range found {
+		ids = append(ids, e.ID)
+	}
+	if expected := []string{"2", "1"}; !slices.Equal(ids, expected) {
+		t.Errorf("expected %v but returned %v", expected, ids)
+	}
+}
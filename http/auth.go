@@ -0,0 +1,312 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/steviebps/realm/helper/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// tokenHashPrefix prefixes the hex encoded SHA-256 hash of a static token
+	tokenHashPrefix = "sha256:"
+	// signedTokenPrefix prefixes the tokens signed with an HMAC key
+	signedTokenPrefix = "realm."
+)
+
+// AuthMethod is the method a caller was authenticated with
+type AuthMethod string
+
+const (
+	TokenAuthMethod AuthMethod = "token"
+	HMACAuthMethod  AuthMethod = "hmac"
+)
+
+// ErrUnauthenticated is returned when the credentials of a request cannot be verified
+var ErrUnauthenticated = errors.New("invalid or expired token")
+
+// AuthConfig configures the authentication of requests. Authentication is disabled when it has no tokens, no keys and no JWT verification
+type AuthConfig struct {
+	// Tokens are the static API tokens accepted by the server
+	Tokens []StaticToken
+	// HMACKeys are the keys that signed tokens are verified with, see SignToken
+	HMACKeys []HMACKey
+	// JWT configures the verification of JWT bearer tokens
+	JWT *JWTConfig
+	// Policies are the access control policies that callers are granted by name. Access to chambers is not restricted when it is empty
+	Policies []Policy
+	// IdentityPolicies maps the names of identities, e.g. the subjects of signed tokens, to the policies they are granted
+	IdentityPolicies map[string][]string
+	// GroupPolicies maps the groups of identities, e.g. the groups claim of a JWT, to the policies they are granted
+	GroupPolicies map[string][]string
+	// AnonymousPolicies are granted to requests without a token
+	AnonymousPolicies []string
+}
+
+// StaticToken is an API token that is only known to the server by its hash
+type StaticToken struct {
+	// Name is the identity of callers presenting the token
+	Name string
+	// Hash is the hash of the token as returned by HashToken
+	Hash string
+	// Policies are granted to callers presenting the token in addition to the policies of its name
+	Policies []string
+}
+
+// HMACKey is a secret that signed tokens are verified with
+type HMACKey struct {
+	// ID is embedded in signed tokens to select the key that verifies them
+	ID     string
+	Secret []byte
+}
+
+func (c AuthConfig) enabled() bool {
+	return len(c.Tokens) > 0 || len(c.HMACKeys) > 0 || c.JWT != nil
+}
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	// Name is the name of the static token, the subject of the signed token or the identity claim of the JWT the caller presented
+	Name   string
+	Method AuthMethod
+	// Groups are the groups the caller is a member of according to its JWT
+	Groups []string
+	// Policies are the names of the access control policies granted to the caller
+	Policies []string
+}
+
+type identityContextKey struct{}
+
+// NewIdentityContext returns a copy of ctx carrying id
+func NewIdentityContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, id)
+}
+
+// IdentityFrom returns the identity of the authenticated caller stored in ctx
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityContextKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+// HashToken returns the hash of a static token in the format expected by StaticToken
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return tokenHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// signedClaims are the claims of a signed token
+type signedClaims struct {
+	KeyID     string `json:"kid"`
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// SignToken returns a token for subject that expires at expiresAt, signed with the HMAC key keyID
+func SignToken(keyID string, secret []byte, subject string, expiresAt time.Time) (string, error) {
+	if keyID == "" || subject == "" {
+		return "", errors.New("key id and subject must not be empty")
+	}
+	if len(secret) == 0 {
+		return "", errors.New("secret must not be empty")
+	}
+	b, err := json.Marshal(signedClaims{KeyID: keyID, Subject: subject, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", err
+	}
+	payload := signedTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sign(secret, payload)), nil
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// authenticator verifies the bearer tokens of requests
+type authenticator struct {
+	tokens        []hashedToken
+	keys          map[string][]byte
+	jwt           *jwtVerifier
+	policies      map[string][]string
+	groupPolicies map[string][]string
+	now           func() time.Time
+}
+
+type hashedToken struct {
+	name     string
+	hash     []byte
+	policies []string
+}
+
+func newAuthenticator(ctx context.Context, c AuthConfig) (*authenticator, error) {
+	a := &authenticator{keys: make(map[string][]byte, len(c.HMACKeys)), policies: c.IdentityPolicies, groupPolicies: c.GroupPolicies, now: time.Now}
+	for _, t := range c.Tokens {
+		if t.Name == "" {
+			return nil, errors.New("static tokens must have a name")
+		}
+		hash, err := hex.DecodeString(strings.TrimPrefix(t.Hash, tokenHashPrefix))
+		if err != nil || !strings.HasPrefix(t.Hash, tokenHashPrefix) || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("hash of token %q must be a hex encoded SHA-256 hash prefixed with %q", t.Name, tokenHashPrefix)
+		}
+		a.tokens = append(a.tokens, hashedToken{name: t.Name, hash: hash, policies: t.Policies})
+	}
+	for _, k := range c.HMACKeys {
+		if k.ID == "" || len(k.Secret) == 0 {
+			return nil, errors.New("HMAC keys must have an id and a secret")
+		}
+		if _, exists := a.keys[k.ID]; exists {
+			return nil, fmt.Errorf("HMAC key %q is configured more than once", k.ID)
+		}
+		a.keys[k.ID] = k.Secret
+	}
+	if c.JWT != nil {
+		v, err := newJWTVerifier(ctx, *c.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+func (a *authenticator) authenticate(ctx context.Context, token string) (*Identity, error) {
+	if strings.HasPrefix(token, signedTokenPrefix) {
+		return a.verifySigned(token)
+	}
+	if a.jwt != nil {
+		if parsed, ok := a.jwt.parseJWT(token); ok {
+			id, err := a.jwt.verify(ctx, parsed)
+			if err != nil {
+				return nil, err
+			}
+			id.Policies = a.identityPolicies(id)
+			return id, nil
+		}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	var found *Identity
+	// every token is compared so that the time taken does not depend on which token matched
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], t.hash) == 1 && found == nil {
+			found = &Identity{Name: t.name, Method: TokenAuthMethod}
+			found.Policies = a.identityPolicies(found, t.policies...)
+		}
+	}
+	if found == nil {
+		return nil, ErrUnauthenticated
+	}
+	return found, nil
+}
+
+func (a *authenticator) verifySigned(token string) (*Identity, error) {
+	payload, sig, ok := cutLast(token, ".")
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(payload, signedTokenPrefix))
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+	var claims signedClaims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, ErrUnauthenticated
+	}
+	secret, exists := a.keys[claims.KeyID]
+	if !exists {
+		return nil, ErrUnauthenticated
+	}
+	actual, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(actual, sign(secret, payload)) {
+		return nil, ErrUnauthenticated
+	}
+	if claims.Subject == "" || !a.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrUnauthenticated
+	}
+	id := &Identity{Name: claims.Subject, Method: HMACAuthMethod}
+	id.Policies = a.identityPolicies(id)
+	return id, nil
+}
+
+// identityPolicies returns the policies granted to the name and the groups of id in addition to policies
+func (a *authenticator) identityPolicies(id *Identity, policies ...string) []string {
+	policies = append(slices.Clone(policies), a.policies[id.Name]...)
+	for _, g := range id.Groups {
+		policies = append(policies, a.groupPolicies[g]...)
+	}
+	return policies
+}
+
+func cutLast(s string, sep string) (string, string, bool) {
+	i := strings.LastIndex(s, sep)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+len(sep):], true
+}
+
+// isMutating reports whether r changes the state of the server. Evaluations are sent with POST but only read chambers
+func isMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "LIST":
+		return false
+	case http.MethodPost:
+		return r.URL.Path != "/v1/evaluate"
+	}
+	return true
+}
+
+// wrapWithAuth authenticates the bearer token of every request that has one and rejects unauthenticated mutating requests.
+// The identity of the caller is stored in the request context and added to the fields of its logger
+func wrapWithAuth(h http.Handler, a *authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		logger := logging.Ctx(ctx)
+
+		unauthorized := func(msg string) {
+			logger.WarnCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path).Msg(msg)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="realm"`)
+			handleError(ctx, w, http.StatusUnauthorized, createResponseWithErrors(nil, []string{msg}))
+		}
+
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			if isMutating(r) {
+				unauthorized("authentication is required")
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, token, _ := strings.Cut(header, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			unauthorized("authorization header must be a bearer token")
+			return
+		}
+		id, err := a.authenticate(ctx, strings.TrimSpace(token))
+		if err != nil {
+			unauthorized(err.Error())
+			return
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("realm.server.identity", id.Name))
+		ctx = NewIdentityContext(ctx, id)
+		ctx = logging.TracedLogger{Logger: logger.With().Str("identity", id.Name).Str("auth_method", string(id.Method)).Logger()}.WithContext(ctx)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
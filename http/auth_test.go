@@ -0,0 +1,102 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestAuth(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stg.Put(context.Background(), storage.StorageEntry{Key: "/", Value: []byte(`{"rules":{}}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := []byte("secret")
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			Tokens:   []realmhttp.StaticToken{{Name: "ci", Hash: realmhttp.HashToken("ci-token")}},
+			HMACKeys: []realmhttp.HMACKey{{ID: "ops", Secret: secret}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(keyID string, secret []byte, expiresAt time.Time) string {
+		t.Helper()
+		token, err := realmhttp.SignToken(keyID, secret, "alice", expiresAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := sign("ops", secret, time.Now().Add(time.Hour))
+	payload, _, _ := strings.Cut(strings.TrimPrefix(valid, "realm."), ".")
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		status        int
+	}{
+		{"anonymous read", http.MethodGet, "", http.StatusOK},
+		{"anonymous write", http.MethodPost, "", http.StatusUnauthorized},
+		{"static token", http.MethodPost, "Bearer ci-token", http.StatusCreated},
+		{"unknown static token", http.MethodPost, "Bearer ci-token2", http.StatusUnauthorized},
+		{"invalid token on read", http.MethodGet, "Bearer ci-token2", http.StatusUnauthorized},
+		{"basic auth", http.MethodPost, "Basic Y2k6Y2ktdG9rZW4=", http.StatusUnauthorized},
+		{"signed token", http.MethodPost, "Bearer " + valid, http.StatusCreated},
+		{"expired signed token", http.MethodPost, "Bearer " + sign("ops", secret, time.Now().Add(-time.Minute)), http.StatusUnauthorized},
+		{"token signed with another secret", http.MethodPost, "Bearer " + sign("ops", []byte("other"), time.Now().Add(time.Hour)), http.StatusUnauthorized},
+		{"token signed with unknown key", http.MethodPost, "Bearer " + sign("dev", secret, time.Now().Add(time.Hour)), http.StatusUnauthorized},
+		{"tampered signed token", http.MethodPost, "Bearer " + strings.Replace(valid, payload, payload+"e30", 1), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/chambers/", strings.NewReader(`{"rules":{}}`))
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("expected a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chambers/", strings.NewReader(`{"rules":{}}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d but returned %d", http.StatusCreated, rec.Code)
+	}
+
+	if _, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg, Auth: realmhttp.AuthConfig{Tokens: []realmhttp.StaticToken{{Name: "ci", Hash: "ci-token"}}}}); err == nil {
+		t.Errorf("expected an error for a token that is not hashed")
+	}
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+// chamberETag returns the entity tag of the chamber stored in entry, which is the quoted revision of the chamber
+func chamberETag(entry *storage.StorageEntry) (string, error) {
+	chamber, err := unmarshalChamber(entry.Key, entry)
+	if err != nil {
+		return "", err
+	}
+	return etagOf(chamber), nil
+}
+
+func etagOf(chamber *realm.Chamber) string {
+	return `"` + chamber.Revision() + `"`
+}
+
+// getChamber retrieves the chamber at path along with its entity tag, which is empty if it could not be computed.
+// The entity tag is of the chamber as it is stored, without the rules inherited from its parents,
+// since that is what writes to the chamber apply to. Both come from the same read so that they are of the same revision
+func getChamber(ctx context.Context, strg storage.Storage, path string) (*storage.StorageEntry, string, error) {
+	var entry, stored *storage.StorageEntry
+	var err error
+	if s, ok := strg.(interface {
+		GetWithLeaf(ctx context.Context, path string) (*storage.StorageEntry, *storage.StorageEntry, error)
+	}); ok {
+		entry, stored, err = s.GetWithLeaf(ctx, path)
+	} else {
+		entry, err = strg.Get(ctx, path)
+		stored = entry
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	etag, _ := chamberETag(stored)
+	return entry, etag, nil
+}
+
+// checkIfMatch returns a precondition failed error unless the If-Match header of r, if any, matches the entity tag of current.
+// It is called within a storage update so that the check and the write are atomic
+func checkIfMatch(r *http.Request, path string, current *storage.StorageEntry) error {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		return nil
+	}
+	if current == nil {
+		return &requestError{http.StatusPreconditionFailed, fmt.Sprintf("chamber %q does not exist", path)}
+	}
+	etag, err := chamberETag(current)
+	if err != nil {
+		return err
+	}
+
+	for tag := range strings.SplitSeq(header, ",") {
+		// weak entity tags never match with the strong comparison If-Match requires
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return &requestError{http.StatusPreconditionFailed, fmt.Sprintf("chamber %q has been modified, its current revision is %s", path, etag)}
+}
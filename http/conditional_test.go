@@ -0,0 +1,88 @@
+package http_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestIfMatch(t *testing.T) {
+	child := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}}}}
+	srv := realmtest.NewServer(t, map[string]*realm.Chamber{
+		"/org/":     {Rules: map[string]*realm.OverrideableRule{"message": {Rule: &realm.Rule{Type: "string", Value: "hello"}}}},
+		"/org/app/": child,
+	})
+
+	do := func(method string, path string, ifMatch string, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+"/v1/"+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		return res
+	}
+
+	// the entity tag is of the chamber as stored, without the inherited rules
+	etag := do(http.MethodGet, "chambers/org/app", "", "").Header.Get("ETag")
+	if expected := `"` + child.Revision() + `"`; etag != expected {
+		t.Fatalf("expected ETag %s but returned %s", expected, etag)
+	}
+
+	const stale = `"0000000000000000"`
+	rule := `{"type":"boolean","value":false}`
+	chamber := `{"rules":{"enabled":` + rule + `}}`
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		ifMatch string
+		body    string
+		status  int
+	}{
+		{"put with stale revision", http.MethodPost, "chambers/org/app", stale, chamber, http.StatusPreconditionFailed},
+		{"patch with stale revision", http.MethodPatch, "chambers/org/app", stale, chamber, http.StatusPreconditionFailed},
+		{"delete with stale revision", http.MethodDelete, "chambers/org/app", stale, "", http.StatusPreconditionFailed},
+		{"put rule with stale revision", http.MethodPut, "rules/org/app?key=enabled", stale, rule, http.StatusPreconditionFailed},
+		{"delete rule with weak revision", http.MethodDelete, "rules/org/app?key=enabled", "W/" + etag, "", http.StatusPreconditionFailed},
+		{"put missing chamber with any revision", http.MethodPost, "chambers/org/missing", "*", chamber, http.StatusPreconditionFailed},
+		{"patch with one of the revisions", http.MethodPatch, "chambers/org/app", stale + ", " + etag, chamber, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := do(tt.method, tt.path, tt.ifMatch, tt.body); res.StatusCode != tt.status {
+				t.Errorf("expected status %d but returned %d", tt.status, res.StatusCode)
+			}
+		})
+	}
+
+	// a write with the current revision returns the next one, after which the previous revision is stale
+	res := do(http.MethodGet, "chambers/org/app", "", "")
+	current := res.Header.Get("ETag")
+	res = do(http.MethodPut, "rules/org/app?key=enabled", current, `{"type":"boolean","value":true}`)
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status %d but returned %d", http.StatusNoContent, res.StatusCode)
+	}
+	next := res.Header.Get("ETag")
+	if next == "" || next == current {
+		t.Errorf("expected a new ETag but returned %q", next)
+	}
+	if res := do(http.MethodPost, "chambers/org/app", current, chamber); res.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d but returned %d", http.StatusPreconditionFailed, res.StatusCode)
+	}
+	if res := do(http.MethodDelete, "chambers/org/app", next, ""); res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status %d but returned %d", http.StatusNoContent, res.StatusCode)
+	}
+}
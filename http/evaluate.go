@@ -0,0 +1,108 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxEvaluationRequestSize limits the size of evaluation requests
+const maxEvaluationRequestSize = 1 << 20
+
+// handleEvaluate resolves the rules of a chamber on POST /v1/evaluate so that clients without an SDK get the same semantics as the Go SDK.
+// The chamber is retrieved from strg, so rules are inherited from parent chambers when the server is configured to be inheritable
+func handleEvaluate(strg storage.Storage, authz *authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := r.Context()
+		logger := logging.Ctx(ctx)
+		errorLog := logger.ErrorCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path)
+		span := trace.SpanFromContext(ctx)
+
+		if r.Method != http.MethodPost {
+			span.SetStatus(codes.Error, "method not allowed")
+			handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+			return
+		}
+
+		var req api.EvaluationRequest
+		if err := utils.ReadInterfaceWith(http.MaxBytesReader(w, r.Body, maxEvaluationRequestSize), &req); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			errorLog.Msg(err.Error())
+			handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{http.StatusText(http.StatusBadRequest)}))
+			return
+		}
+		if req.Path == "" {
+			handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{"path must not be empty"}))
+			return
+		}
+
+		p := utils.EnsureTrailingSlash("/" + strings.TrimPrefix(req.Path, "/"))
+		span.SetAttributes(attribute.String("realm.server.logicalPath", p), attribute.String("realm.server.evaluate.version", req.Version))
+
+		if !authz.allowed(ctx, p, ReadCapability) {
+			msg := fmt.Sprintf("%s is not permitted on %s", ReadCapability, p)
+			span.SetStatus(codes.Error, msg)
+			handleUnauthorized(ctx, w, msg)
+			return
+		}
+
+		entry, err := strg.Get(ctx, p)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			errorLog.Msg(err.Error())
+			status := http.StatusInternalServerError
+			var nfError *storage.NotFoundError
+			if errors.As(err, &nfError) {
+				status = http.StatusNotFound
+			}
+			handleError(ctx, w, status, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+
+		var chamber realm.Chamber
+		if err := json.Unmarshal(entry.Value, &chamber); err != nil {
+			err = fmt.Errorf("could not unmarshal chamber %q: %w", p, err)
+			span.SetStatus(codes.Error, err.Error())
+			errorLog.Msg(err.Error())
+			handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+
+		keys := req.Keys
+		if len(keys) == 0 {
+			for key := range chamber.Rules {
+				keys = append(keys, key)
+			}
+		}
+
+		res := api.EvaluationResponse{Path: p, Revision: chamber.Revision(), Rules: make(map[string]api.EvaluatedRule, len(keys))}
+		c := realm.NewChamberEntry(&chamber, req.Version)
+		for _, key := range keys {
+			e := c.Evaluate(key)
+			rule := api.EvaluatedRule{Type: e.Type, Value: e.Value, Reason: string(e.Reason), Variant: e.Variant}
+			if e.Err != nil {
+				rule.Error = e.Err.Error()
+			}
+			res.Rules[key] = rule
+		}
+
+		raw, err := json.Marshal(res)
+		if err != nil {
+			handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+		handleOk(w, createResponseWithErrors(raw, nil))
+	})
+}
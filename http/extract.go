@@ -0,0 +1,71 @@
+package http
+
+import (
+	"net/http"
+
+	realm "github.com/steviebps/realm/pkg"
+)
+
+// Extractor reads evaluation attributes from an incoming request into the evaluation context of the request.
+// Extractors are applied in order, so later extractors take precedence over earlier ones
+type Extractor func(r *http.Request, ec *realm.EvaluationContext)
+
+// VersionFromHeader extracts the application version from the request header
+func VersionFromHeader(header string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		if v := r.Header.Get(header); v != "" {
+			ec.Version = v
+		}
+	}
+}
+
+// VersionFromQuery extracts the application version from the query parameter of the request
+func VersionFromQuery(param string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		if v := r.URL.Query().Get(param); v != "" {
+			ec.Version = v
+		}
+	}
+}
+
+// VersionFromCookie extracts the application version from the request cookie
+func VersionFromCookie(name string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			ec.Version = c.Value
+		}
+	}
+}
+
+// AttributeFromHeader extracts the evaluation attribute from the request header
+func AttributeFromHeader(attribute string, header string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		setAttribute(ec, attribute, r.Header.Get(header))
+	}
+}
+
+// AttributeFromQuery extracts the evaluation attribute from the query parameter of the request
+func AttributeFromQuery(attribute string, param string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		setAttribute(ec, attribute, r.URL.Query().Get(param))
+	}
+}
+
+// AttributeFromCookie extracts the evaluation attribute from the request cookie
+func AttributeFromCookie(attribute string, name string) Extractor {
+	return func(r *http.Request, ec *realm.EvaluationContext) {
+		if c, err := r.Cookie(name); err == nil {
+			setAttribute(ec, attribute, c.Value)
+		}
+	}
+}
+
+func setAttribute(ec *realm.EvaluationContext, attribute string, value string) {
+	if value == "" {
+		return
+	}
+	if ec.Attributes == nil {
+		ec.Attributes = make(map[string]string)
+	}
+	ec.Attributes[attribute] = value
+}
@@ -0,0 +1,63 @@
+package http_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	realmhttp "github.com/steviebps/realm/http"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestRealmHandlerExtractors(t *testing.T) {
+	fake := realmtest.New(t, &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"message": {
+			Rule: &realm.Rule{Type: "string", Value: "default"},
+			Overrides: []*realm.Override{
+				{Rule: &realm.Rule{Type: "string", Value: "v2"}, MinimumVersion: "v2.0.0", MaximumVersion: "v2.9.9"},
+			},
+		},
+	}}, realm.WithVersion("v1.0.0"))
+	rlm := fake.Realm()
+
+	h := realmhttp.RealmHandler(rlm, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message, _ := rlm.String(r.Context(), "message", "")
+		ec, _ := realm.EvaluationContextFrom(r.Context())
+		fmt.Fprintf(w, "%s %s", message, ec.Attributes["tenant"])
+	}),
+		realmhttp.VersionFromQuery("version"),
+		realmhttp.VersionFromHeader("X-App-Version"),
+		realmhttp.AttributeFromCookie("tenant", "tenant"),
+	)
+
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		cookie   string
+		expected string
+	}{
+		{"realm version", "/", "", "", "default "},
+		{"version from query", "/?version=v2.1.0", "", "", "v2 "},
+		{"header takes precedence over query", "/?version=v2.1.0", "v1.5.0", "", "default "},
+		{"attribute from cookie", "/", "v2.0.0", "acme", "v2 acme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-App-Version", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "tenant", Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := rec.Body.String(); got != tt.expected {
+				t.Errorf("expected %q but returned %q", tt.expected, got)
+			}
+		})
+	}
+}
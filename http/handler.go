@@ -8,19 +8,24 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/steviebps/realm/api"
 	"github.com/steviebps/realm/helper/logging"
 	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/audit"
 	"github.com/steviebps/realm/pkg/storage"
 	"github.com/steviebps/realm/utils"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -38,12 +43,38 @@ var errorCounter, _ = meter.Int64Counter(
 type HandlerConfig struct {
 	Storage        storage.Storage
 	RequestTimeout time.Duration
+	// UsageStorage stores the rule usage reported by SDKs. Usage is kept in memory when it is nil
+	UsageStorage storage.Storage
+	// Auth configures the tokens that requests are authenticated with. Mutating requests are accepted from anyone when it is empty
+	Auth AuthConfig
+	// AuditSink records every change made to chambers. Changes are not recorded when it is nil
+	AuditSink audit.Sink
+	// HistoryStorage stores the revisions of every chamber. Revisions are kept in memory when it is nil
+	HistoryStorage storage.Storage
+	// HistoryRetention is the number of revisions kept per chamber. DefaultHistoryRetention is used when it is not positive
+	HistoryRetention int
+	// Version is the version of the server reported by /v1/sys/version. DefaultVersion is reported when it is empty
+	Version string
 }
 
-func RealmHandler(rlm *realm.Realm, h http.Handler) http.Handler {
+// RealmHandler associates the current chamber of rlm with every request so that it is consistent for the whole request.
+// The extractors read the evaluation context of each request, e.g. the caller's application version, so that overrides are evaluated per caller.
+// The chamber revision the caller evaluated is read from the request's OpenTelemetry baggage, see realm.WithRevisionPinning
+func RealmHandler(rlm *realm.Realm, h http.Handler, extractors ...Extractor) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		req := r.WithContext(rlm.NewContext(r.Context()))
-		h.ServeHTTP(w, req)
+		ctx := r.Context()
+		if baggage.FromContext(ctx).Member(realm.RevisionBaggageKey).Key() == "" {
+			ctx = propagation.Baggage{}.Extract(ctx, propagation.HeaderCarrier(r.Header))
+		}
+		ctx = rlm.NewContext(ctx)
+		if len(extractors) > 0 {
+			var ec realm.EvaluationContext
+			for _, extract := range extractors {
+				extract(r, &ec)
+			}
+			ctx = realm.NewEvaluationContext(ctx, ec)
+		}
+		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -54,10 +85,24 @@ func NewHandler(ctx context.Context, config HandlerConfig) (http.Handler, error)
 	if config.RequestTimeout <= 0 {
 		config.RequestTimeout = DefaultHandlerTimeout
 	}
-	return handle(ctx, config), nil
+	if config.UsageStorage == nil {
+		stg, err := storage.NewInmemStorage(nil)
+		if err != nil {
+			return nil, err
+		}
+		config.UsageStorage = stg
+	}
+	if config.HistoryStorage == nil {
+		stg, err := storage.NewInmemStorage(nil)
+		if err != nil {
+			return nil, err
+		}
+		config.HistoryStorage = stg
+	}
+	return handle(ctx, config)
 }
 
-func handle(ctx context.Context, hc HandlerConfig) http.Handler {
+func handle(ctx context.Context, hc HandlerConfig) (http.Handler, error) {
 	logger := logging.Ctx(ctx)
 	mux := http.NewServeMux()
 
@@ -67,10 +112,47 @@ func handle(ctx context.Context, hc HandlerConfig) http.Handler {
 		mux.Handle("/ui/", otelhttp.NewHandler(handleUIEmpty(), "/ui/"))
 	}
 
-	mux.Handle("/v1/chambers/", otelhttp.NewHandler(handleChambers(hc.Storage), "/v1/chambers/"))
+	authz, err := newAuthorizer(hc.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	hist := newHistoryStore(hc.HistoryStorage, hc.HistoryRetention)
+	rec := changeRecorders{hist}
+	aud := newAuditor(hc.AuditSink)
+	if aud != nil {
+		rec = append(rec, aud)
+	}
+	chambers := handleChambers(hc.Storage, authz, rec)
+	mux.Handle("/v1/chambers/", otelhttp.NewHandler(chambers, "/v1/chambers/"))
+	mux.Handle(rulesEndpoint, otelhttp.NewHandler(chambers, rulesEndpoint))
+	mux.Handle("/v1/audit", otelhttp.NewHandler(handleAudit(aud, authz), "/v1/audit"))
+	mux.Handle("/v1/history/", otelhttp.NewHandler(handleHistory(hc.Storage, hist, authz, rec), "/v1/history/"))
+
+	mux.Handle("/v1/evaluate", otelhttp.NewHandler(handleEvaluate(hc.Storage, authz), "/v1/evaluate"))
+
+	usage := handleUsage(hc.Storage, &usageStore{strg: hc.UsageStorage}, authz)
+	mux.Handle("/v1/usage", otelhttp.NewHandler(usage, "/v1/usage"))
+	mux.Handle("/v1/usage/", otelhttp.NewHandler(usage, "/v1/usage/"))
+
+	var h http.Handler = mux
+	if hc.Auth.enabled() {
+		a, err := newAuthenticator(ctx, hc.Auth)
+		if err != nil {
+			return nil, err
+		}
+		h = wrapWithAuth(h, a)
+	}
+
+	// the system endpoints are called by load balancers and probes which do not authenticate
+	sys := http.NewServeMux()
+	sys.Handle("/v1/sys/health", otelhttp.NewHandler(handleLiveness(), "/v1/sys/health"))
+	sys.Handle("/v1/sys/ready", otelhttp.NewHandler(handleReadiness(hc.Storage), "/v1/sys/ready"))
+	sys.Handle("/v1/sys/version", otelhttp.NewHandler(handleVersion(hc.Version), "/v1/sys/version"))
+	sys.Handle("/", h)
 
-	timeoutHandler := wrapWithTimeout(mux, hc.RequestTimeout)
-	return wrapCommonHandler(timeoutHandler, logger)
+	timeoutHandler := wrapWithTimeout(sys, hc.RequestTimeout)
+	return wrapCommonHandler(timeoutHandler, logger), nil
 }
 
 func wrapWithTimeout(h http.Handler, t time.Duration) http.Handler {
@@ -139,7 +221,7 @@ func handleError(ctx context.Context, w http.ResponseWriter, status int, resp ap
 	utils.WriteInterfaceWith(w, resp, true)
 }
 
-func handleChambers(strg storage.Storage) http.Handler {
+func handleChambers(strg storage.Storage, authz *authorizer, rec changeRecorder) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 		ctx := r.Context()
@@ -148,11 +230,42 @@ func handleChambers(strg storage.Storage) http.Handler {
 		span := trace.SpanFromContext(ctx)
 
 		req := buildAgentRequest(r)
+		w.Header().Set(RequestIDHeader, req.ID)
 		span.SetAttributes(attribute.String("realm.server.logicalPath", req.Path), attribute.String("realm.server.operation", string(req.Operation)))
 
+		if req.RuleKey == "" && strings.HasPrefix(r.URL.Path, rulesEndpoint) {
+			span.SetStatus(codes.Error, "key must not be empty")
+			handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{"key must not be empty"}))
+			return
+		}
+
+		if capability := operationCapability(req.Operation, req.RuleKey); !authz.allowed(ctx, req.Path, capability) {
+			msg := fmt.Sprintf("%s is not permitted on %s", capability, req.Path)
+			span.SetStatus(codes.Error, msg)
+			logger.WarnCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path).Msg(msg)
+			handleUnauthorized(ctx, w, msg)
+			return
+		}
+
+		if req.RuleKey != "" {
+			handleRule(w, req, strg, rec)
+			return
+		}
+
+		// writes are applied within a storage update so that the If-Match precondition and the write are atomic
+		update := func(fn storage.UpdateFunc) bool {
+			if err := updateChamber(ctx, strg, rec, req, fn); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				errorLog.Msg(err.Error())
+				handleError(ctx, w, errorStatus(err), createResponseWithErrors(nil, []string{err.Error()}))
+				return false
+			}
+			return true
+		}
+
 		switch req.Operation {
 		case GetOperation:
-			entry, err := strg.Get(ctx, req.Path)
+			entry, etag, err := getChamber(ctx, strg, req.Path)
 			if err != nil {
 				span.SetStatus(codes.Error, err.Error())
 				errorLog.Msg(err.Error())
@@ -166,6 +279,9 @@ func handleChambers(strg storage.Storage) http.Handler {
 				return
 			}
 
+			if etag != "" {
+				w.Header().Set("ETag", etag)
+			}
 			handleOk(w, createResponseWithErrors(entry.Value, nil))
 			return
 
@@ -195,94 +311,69 @@ func handleChambers(strg storage.Storage) http.Handler {
 			}
 
 			// store the entry if the format is correct
-			entry := storage.StorageEntry{Key: req.Path, Value: b}
-			if err := strg.Put(ctx, entry); err != nil {
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
-				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+			ok := update(func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+				if err := checkIfMatch(r, req.Path, current); err != nil {
+					return nil, err
+				}
+				return &storage.StorageEntry{Key: req.Path, Value: b}, nil
+			})
+			if !ok {
 				return
 			}
 
+			w.Header().Set("ETag", etagOf(&putChamber))
 			handleWithStatus(w, http.StatusCreated, nil)
 			return
 
 		case PatchOperation:
-			var patchChamber realm.Chamber
-
-			// ensure data is in correct format
-			if err := utils.ReadInterfaceWith(r.Body, &patchChamber); err != nil {
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
-				if errors.Is(err, io.EOF) {
-					err = errors.New("request body must not be empty")
-				} else {
-					err = errors.New(http.StatusText(http.StatusBadRequest))
-				}
-				handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{err.Error()}))
-				return
-			}
-
-			entry, err := strg.Get(ctx, req.Path)
+			patch, err := readPatch(w, r)
 			if err != nil {
-				var nfError *storage.NotFoundError
-				if errors.As(err, &nfError) {
-					err = fmt.Errorf("cannot patch a resource that does not exist: %w", err)
-				}
-
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
-
-				handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{err.Error()}))
-				return
-			}
-
-			current := realm.Chamber{}
-			if err := json.Unmarshal(entry.Value, &current); err != nil {
-				err = fmt.Errorf("could not unmarshal current chamber while patching: %w", err)
-
 				span.SetStatus(codes.Error, err.Error())
 				errorLog.Msg(err.Error())
-
-				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				handleError(ctx, w, errorStatus(err), createResponseWithErrors(nil, []string{err.Error()}))
 				return
 			}
 
-			current.OverwriteFrom(&patchChamber)
-			b, err := json.Marshal(&current)
-			if err != nil {
-				err = fmt.Errorf("could not patch while merging chambers: %w", err)
-
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
+			var etag string
+			ok := update(func(entry *storage.StorageEntry) (*storage.StorageEntry, error) {
+				if entry == nil {
+					return nil, &requestError{http.StatusBadRequest, fmt.Sprintf("cannot patch a resource that does not exist: %s does not exist", req.Path)}
+				}
+				if err := checkIfMatch(r, req.Path, entry); err != nil {
+					return nil, err
+				}
 
-				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
-				return
-			}
+				current, err := patch(entry.Value)
+				if err != nil {
+					return nil, err
+				}
 
-			// store the entry if the format is correct
-			patchEntry := storage.StorageEntry{Key: req.Path, Value: b}
-			if err := strg.Put(ctx, patchEntry); err != nil {
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
-				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				etag = etagOf(current)
+				b, err := json.Marshal(current)
+				if err != nil {
+					return nil, fmt.Errorf("could not patch while merging chambers: %w", err)
+				}
+				return &storage.StorageEntry{Key: req.Path, Value: b}, nil
+			})
+			if !ok {
 				return
 			}
 
+			w.Header().Set("ETag", etag)
 			handleOk(w, nil)
 			return
 
 		case DeleteOperation:
-			if err := strg.Delete(ctx, req.Path); err != nil {
-				span.SetStatus(codes.Error, err.Error())
-				errorLog.Msg(err.Error())
-
-				var nfError *storage.NotFoundError
-				if errors.As(err, &nfError) {
-					handleError(ctx, w, http.StatusNotFound, createResponseWithErrors(nil, []string{nfError.Error()}))
-					return
+			ok := update(func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+				if current == nil {
+					return nil, &storage.NotFoundError{Key: req.Path}
 				}
-
-				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				if err := checkIfMatch(r, req.Path, current); err != nil {
+					return nil, err
+				}
+				return nil, nil
+			})
+			if !ok {
 				return
 			}
 			handleOk(w, nil)
@@ -300,6 +391,10 @@ func handleChambers(strg storage.Storage) http.Handler {
 				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
 				return
 			}
+			// only the chambers that the caller has access to, or has access below, are listed
+			names = slices.DeleteFunc(names, func(name string) bool {
+				return !authz.visible(ctx, req.Path+strings.Trim(name, "/")+"/")
+			})
 			raw, err := json.Marshal(names)
 			if err != nil {
 				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
@@ -316,6 +411,38 @@ func handleChambers(strg storage.Storage) http.Handler {
 	})
 }
 
+// changeRecorder is notified of every change made to a chamber, before and after are nil when the chamber does not exist
+type changeRecorder interface {
+	record(ctx context.Context, req *AgentRequest, before *storage.StorageEntry, after *storage.StorageEntry)
+}
+
+// changeRecorders notifies each of its recorders in order
+type changeRecorders []changeRecorder
+
+func (rs changeRecorders) record(ctx context.Context, req *AgentRequest, before *storage.StorageEntry, after *storage.StorageEntry) {
+	for _, r := range rs {
+		r.record(ctx, req, before, after)
+	}
+}
+
+// updateChamber applies fn to the chamber of req within a storage update and records the change, e.g. in the audit log
+func updateChamber(ctx context.Context, strg storage.Storage, rec changeRecorder, req *AgentRequest, fn storage.UpdateFunc) error {
+	var before, after *storage.StorageEntry
+	err := storage.Update(ctx, strg, req.Path, func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+		next, err := fn(current)
+		if err != nil {
+			return nil, err
+		}
+		before, after = current, next
+		return next, nil
+	})
+	if err != nil {
+		return err
+	}
+	rec.record(ctx, req, before, after)
+	return nil
+}
+
 func handleUIEmpty() http.Handler {
 	stubHTML := `
 	<!DOCTYPE html>
@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultHistoryRetention is the number of revisions kept per chamber when HandlerConfig.HistoryRetention is not positive
+const DefaultHistoryRetention = 25
+
+// maxRollbackRequestSize limits the size of rollback requests
+const maxRollbackRequestSize = 1 << 10
+
+// historyRecord is a revision of a chamber along with its content
+type historyRecord struct {
+	api.ChamberRevision
+	Chamber json.RawMessage `json:"chamber,omitempty"`
+}
+
+// historyStore keeps the last revisions of every chamber path.
+// The revisions of a chamber are stored as a single entry keyed by the chamber path, from the oldest to the most recent
+type historyStore struct {
+	strg      storage.Storage
+	retention int
+	now       func() time.Time
+}
+
+func newHistoryStore(strg storage.Storage, retention int) *historyStore {
+	if retention <= 0 {
+		retention = DefaultHistoryRetention
+	}
+	return &historyStore{strg: strg, retention: retention, now: time.Now}
+}
+
+func (hs *historyStore) get(ctx context.Context, p string) ([]historyRecord, error) {
+	entry, err := hs.strg.Get(ctx, p)
+	if err != nil {
+		var nfError *storage.NotFoundError
+		if errors.As(err, &nfError) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return unmarshalHistory(p, entry)
+}
+
+func unmarshalHistory(p string, entry *storage.StorageEntry) ([]historyRecord, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	var records []historyRecord
+	if err := json.Unmarshal(entry.Value, &records); err != nil {
+		return nil, fmt.Errorf("could not unmarshal history of %q: %w", p, err)
+	}
+	return records, nil
+}
+
+// find returns the most recent record of the revision of the chamber at p
+func (hs *historyStore) find(ctx context.Context, p string, revision string) (*historyRecord, error) {
+	records, err := hs.get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range slices.Backward(records) {
+		if r.Revision == revision && !r.Deleted {
+			return &r, nil
+		}
+	}
+	return nil, &requestError{http.StatusNotFound, fmt.Sprintf("revision %q of %q does not exist", revision, p)}
+}
+
+// newHistoryRecord returns the record of the chamber stored in entry, or of its deletion when entry is nil
+func newHistoryRecord(p string, entry *storage.StorageEntry, t time.Time) (historyRecord, error) {
+	record := historyRecord{ChamberRevision: api.ChamberRevision{Time: t, Deleted: entry == nil}}
+	if entry == nil {
+		return record, nil
+	}
+	c, err := unmarshalChamber(p, entry)
+	if err != nil {
+		return record, err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return record, err
+	}
+	record.Revision = c.Revision()
+	record.Chamber = b
+	return record, nil
+}
+
+// record adds the chamber after the change made by req to its history. The chamber before the change is added first
+// if it is not the most recent revision, e.g. when it was written before history was kept.
+// The change has already been applied so a failure to record it is only logged
+func (hs *historyStore) record(ctx context.Context, req *AgentRequest, before *storage.StorageEntry, after *storage.StorageEntry) {
+	now := hs.now().UTC()
+	err := storage.Update(ctx, hs.strg, req.Path, func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+		records, err := unmarshalHistory(req.Path, current)
+		if err != nil {
+			return nil, err
+		}
+		latest := func() *historyRecord {
+			if len(records) == 0 {
+				return nil
+			}
+			return &records[len(records)-1]
+		}
+
+		if before != nil {
+			prev, err := newHistoryRecord(req.Path, before, now)
+			if err != nil {
+				return nil, err
+			}
+			if l := latest(); l == nil || l.Deleted || l.Revision != prev.Revision {
+				records = append(records, prev)
+			}
+		}
+
+		next, err := newHistoryRecord(req.Path, after, now)
+		if err != nil {
+			return nil, err
+		}
+		next.RequestID = req.ID
+		if id, ok := IdentityFrom(ctx); ok {
+			next.Identity = id.Name
+		}
+		l := latest()
+		if l == nil || l.Deleted != next.Deleted || l.Revision != next.Revision {
+			records = append(records, next)
+		} else {
+			// the content did not change, only who wrote it last is updated
+			*l = next
+		}
+
+		if len(records) > hs.retention {
+			records = records[len(records)-hs.retention:]
+		}
+		b, err := json.Marshal(records)
+		if err != nil {
+			return nil, err
+		}
+		return &storage.StorageEntry{Key: req.Path, Value: b}, nil
+	})
+	if err != nil {
+		logging.Ctx(ctx).ErrorCtx(ctx).Str("path", req.Path).Str("request_id", req.ID).Msgf("could not record history: %s", err.Error())
+	}
+}
+
+// handleHistory serves the revision history of chambers on /v1/history/{path}.
+// GET lists the revisions of the chamber, most recent first, or returns the chamber at the revision query parameter.
+// POST rolls the chamber back to the revision of the request body, writing it as a new revision
+func handleHistory(strg storage.Storage, hs *historyStore, authz *authorizer, rec changeRecorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := r.Context()
+		logger := logging.Ctx(ctx)
+		errorLog := logger.ErrorCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path)
+		span := trace.SpanFromContext(ctx)
+
+		fail := func(err error) {
+			span.SetStatus(codes.Error, err.Error())
+			errorLog.Msg(err.Error())
+			handleError(ctx, w, errorStatus(err), createResponseWithErrors(nil, []string{err.Error()}))
+		}
+
+		unescaped, _ := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/v1/history"))
+		p := utils.EnsureTrailingSlash("/" + strings.TrimPrefix(unescaped, "/"))
+		if err := storage.ValidatePath(p); err != nil {
+			fail(&requestError{http.StatusBadRequest, fmt.Sprintf("invalid path %q: %s", p, err.Error())})
+			return
+		}
+		span.SetAttributes(attribute.String("realm.server.logicalPath", p))
+
+		capability := ReadCapability
+		if r.Method == http.MethodPost {
+			capability = WriteCapability
+		}
+		if !authz.allowed(ctx, p, capability) {
+			msg := fmt.Sprintf("%s is not permitted on %s", capability, p)
+			span.SetStatus(codes.Error, msg)
+			handleUnauthorized(ctx, w, msg)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if revision := r.URL.Query().Get("revision"); revision != "" {
+				record, err := hs.find(ctx, p, revision)
+				if err != nil {
+					fail(err)
+					return
+				}
+				w.Header().Set("ETag", `"`+record.Revision+`"`)
+				handleOk(w, createResponseWithErrors(record.Chamber, nil))
+				return
+			}
+
+			records, err := hs.get(ctx, p)
+			if err != nil {
+				fail(err)
+				return
+			}
+			revisions := make([]api.ChamberRevision, 0, len(records))
+			for _, r := range slices.Backward(records) {
+				revisions = append(revisions, r.ChamberRevision)
+			}
+			raw, err := json.Marshal(revisions)
+			if err != nil {
+				fail(err)
+				return
+			}
+			handleOk(w, createResponseWithErrors(raw, nil))
+
+		case http.MethodPost:
+			var rollback api.RollbackRequest
+			if err := utils.ReadInterfaceWith(http.MaxBytesReader(w, r.Body, maxRollbackRequestSize), &rollback); err != nil || rollback.Revision == "" {
+				msg := "request body must contain the revision to roll back to"
+				if err != nil && !errors.Is(err, io.EOF) {
+					msg = http.StatusText(http.StatusBadRequest)
+				}
+				fail(&requestError{http.StatusBadRequest, msg})
+				return
+			}
+			record, err := hs.find(ctx, p, rollback.Revision)
+			if err != nil {
+				fail(err)
+				return
+			}
+
+			req := &AgentRequest{Request: r, ID: requestID(r), Operation: PutOperation, Path: p}
+			w.Header().Set(RequestIDHeader, req.ID)
+			err = updateChamber(ctx, strg, rec, req, func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+				if err := checkIfMatch(r, p, current); err != nil {
+					return nil, err
+				}
+				return &storage.StorageEntry{Key: p, Value: record.Chamber}, nil
+			})
+			if err != nil {
+				fail(err)
+				return
+			}
+			w.Header().Set("ETag", `"`+record.Revision+`"`)
+			handleOk(w, nil)
+
+		default:
+			span.SetStatus(codes.Error, "method not allowed")
+			handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestHistoryRetention(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg, HistoryRetention: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var etags []string
+	for i := range 5 {
+		rec := do(http.MethodPost, "/v1/chambers/app", fmt.Sprintf(`{"rules":{"count":{"type":"number","value":%d}}}`, i))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d but returned %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		etags = append(etags, strings.Trim(rec.Header().Get("ETag"), `"`))
+	}
+	// writing the same content again does not add a revision
+	do(http.MethodPost, "/v1/chambers/app", `{"rules":{"count":{"type":"number","value":4}}}`)
+
+	rec := do(http.MethodGet, "/v1/history/app", "")
+	var res api.HTTPErrorAndDataResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var revisions []api.ChamberRevision
+	if err := json.Unmarshal(res.Data, &revisions); err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 3 || revisions[0].Revision != etags[4] || revisions[2].Revision != etags[2] {
+		t.Errorf("expected the last 3 revisions %v but returned %+v", etags[2:], revisions)
+	}
+
+	if rec := do(http.MethodGet, "/v1/history/app?revision="+etags[1], ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a revision past retention but returned %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/history/app", `{"revision":"`+etags[2]+`"}`); rec.Code != http.StatusNoContent || rec.Header().Get("ETag") != `"`+etags[2]+`"` {
+		t.Errorf("expected rollback with ETag %s but returned %d %s", etags[2], rec.Code, rec.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,286 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/steviebps/realm/helper/logging"
+)
+
+const (
+	// DefaultJWKSRefreshInterval is the minimum time between two loads of the JWKS when a token is signed with an unknown key
+	DefaultJWKSRefreshInterval = 5 * time.Minute
+	// jwksFetchTimeout bounds the time taken to fetch the JWKS from its URL
+	jwksFetchTimeout = 10 * time.Second
+	// maxJWKSSize limits the size of the JWKS read from its file or URL
+	maxJWKSSize = 1 << 20
+	// jwtLeeway is the clock skew tolerated when validating the time claims of a JWT
+	jwtLeeway = time.Minute
+)
+
+// JWTAuthMethod is the method of callers authenticated with a JWT
+const JWTAuthMethod AuthMethod = "jwt"
+
+// defaultJWTAlgorithms are the signature algorithms accepted when JWTConfig.Algorithms is empty
+var defaultJWTAlgorithms = []jose.SignatureAlgorithm{jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512, jose.ES256, jose.ES384, jose.ES512, jose.EdDSA}
+
+// JWTConfig configures the verification of JWT bearer tokens, e.g. the ID tokens of an OIDC provider
+type JWTConfig struct {
+	// JWKSFile is the path of the JSON Web Key Set that tokens are verified with
+	JWKSFile string
+	// JWKSURL is the URL of the JSON Web Key Set that tokens are verified with, e.g. the jwks_uri of an OIDC provider
+	JWKSURL string
+	// Issuer must match the iss claim of tokens
+	Issuer string
+	// Audiences must contain one of the values of the aud claim of tokens
+	Audiences []string
+	// IdentityClaim is the claim that the name of the identity is read from, "sub" when it is empty
+	IdentityClaim string
+	// GroupsClaim is the claim listing the groups of the caller, whose policies are granted through AuthConfig.GroupPolicies
+	GroupsClaim string
+	// Algorithms are the accepted signature algorithms. Asymmetric algorithms are accepted when it is empty
+	Algorithms []string
+	// RefreshInterval is the minimum time between two loads of the JWKS. DefaultJWKSRefreshInterval is used when it is zero
+	RefreshInterval time.Duration
+	// Client fetches the JWKS from JWKSURL. A client with a timeout is used when it is nil
+	Client *http.Client
+}
+
+// jwtVerifier verifies JWT bearer tokens and maps their claims to identities
+type jwtVerifier struct {
+	keys          *jwks
+	algorithms    []jose.SignatureAlgorithm
+	expected      jwt.Expected
+	identityClaim string
+	groupsClaim   string
+}
+
+func newJWTVerifier(ctx context.Context, c JWTConfig) (*jwtVerifier, error) {
+	if (c.JWKSFile == "") == (c.JWKSURL == "") {
+		return nil, errors.New("JWT verification requires either a JWKS file or a JWKS URL")
+	}
+	if c.Issuer == "" || len(c.Audiences) == 0 {
+		return nil, errors.New("JWT verification requires an issuer and at least one audience")
+	}
+
+	v := &jwtVerifier{
+		algorithms:    defaultJWTAlgorithms,
+		expected:      jwt.Expected{Issuer: c.Issuer, AnyAudience: c.Audiences},
+		identityClaim: c.IdentityClaim,
+		groupsClaim:   c.GroupsClaim,
+	}
+	if v.identityClaim == "" {
+		v.identityClaim = "sub"
+	}
+	if len(c.Algorithms) > 0 {
+		v.algorithms = make([]jose.SignatureAlgorithm, 0, len(c.Algorithms))
+		for _, alg := range c.Algorithms {
+			if alg == string(jose.HS256) || alg == string(jose.HS384) || alg == string(jose.HS512) || alg == "none" {
+				return nil, fmt.Errorf("JWT algorithm %q is not supported, tokens must be signed with an asymmetric key", alg)
+			}
+			v.algorithms = append(v.algorithms, jose.SignatureAlgorithm(alg))
+		}
+	}
+
+	v.keys = &jwks{interval: c.RefreshInterval, now: time.Now}
+	if v.keys.interval <= 0 {
+		v.keys.interval = DefaultJWKSRefreshInterval
+	}
+	if c.JWKSFile != "" {
+		v.keys.load = func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			f, err := os.Open(c.JWKSFile)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			return readJWKS(f)
+		}
+	} else {
+		client := c.Client
+		if client == nil {
+			client = &http.Client{Timeout: jwksFetchTimeout}
+		}
+		v.keys.load = func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+			return fetchJWKS(ctx, client, c.JWKSURL)
+		}
+	}
+
+	// a missing file is a configuration error while the provider serving the URL may only be unavailable for now
+	if err := v.keys.refresh(ctx); err != nil {
+		if c.JWKSFile != "" {
+			return nil, err
+		}
+		logging.Ctx(ctx).WarnCtx(ctx).Str("url", c.JWKSURL).Msg(err.Error())
+	}
+	return v, nil
+}
+
+// verify returns the identity of a JWT. The policies of the identity are not set
+func (v *jwtVerifier) verify(ctx context.Context, token *jwt.JSONWebToken) (*Identity, error) {
+	if len(token.Headers) != 1 {
+		return nil, ErrUnauthenticated
+	}
+	key, err := v.keys.key(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		logging.Ctx(ctx).WarnCtx(ctx).Msg(err.Error())
+		return nil, ErrUnauthenticated
+	}
+
+	var claims jwt.Claims
+	var custom map[string]any
+	if err := token.Claims(key.Key, &claims, &custom); err != nil {
+		return nil, ErrUnauthenticated
+	}
+	if claims.Expiry == nil || claims.ValidateWithLeeway(v.expected.WithTime(v.keys.now()), jwtLeeway) != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	name, _ := custom[v.identityClaim].(string)
+	if name == "" {
+		return nil, ErrUnauthenticated
+	}
+	id := &Identity{Name: name, Method: JWTAuthMethod}
+	if v.groupsClaim != "" {
+		switch groups := custom[v.groupsClaim].(type) {
+		case string:
+			id.Groups = []string{groups}
+		case []any:
+			for _, g := range groups {
+				if s, ok := g.(string); ok {
+					id.Groups = append(id.Groups, s)
+				}
+			}
+		}
+	}
+	return id, nil
+}
+
+// parseJWT parses token if it is a compact JWS, e.g. header.payload.signature
+func (v *jwtVerifier) parseJWT(token string) (*jwt.JSONWebToken, bool) {
+	if strings.Count(token, ".") != 2 {
+		return nil, false
+	}
+	parsed, err := jwt.ParseSigned(token, v.algorithms)
+	return parsed, err == nil
+}
+
+// jwks caches a JSON Web Key Set, loading it again when a token is signed with an unknown key
+type jwks struct {
+	// mu guards the fields below it, it is never held while the key set is loaded
+	mu  sync.Mutex
+	set *jose.JSONWebKeySet
+	// loadedAt is the time of the last successful load, failed loads are retried on the next unknown key
+	loadedAt time.Time
+	// loading is closed once the load in progress completes, it is nil when no load is in progress
+	loading  chan struct{}
+	interval time.Duration
+	load     func(ctx context.Context) (*jose.JSONWebKeySet, error)
+	now      func() time.Time
+}
+
+func (ks *jwks) refresh(ctx context.Context) error {
+	set, err := ks.load(ctx)
+	if err != nil {
+		return fmt.Errorf("could not load JWKS: %w", err)
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.set = set
+	ks.loadedAt = ks.now()
+	return nil
+}
+
+// key returns the public key with the id kid, loading the key set again at most once per interval if it is not found.
+// Keys already loaded are returned while the key set is loaded, and the callers looking for unknown keys wait for the load in progress
+func (ks *jwks) key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	ks.mu.Lock()
+	if key := ks.find(kid); key != nil {
+		ks.mu.Unlock()
+		return key, nil
+	}
+	loading := ks.loading
+	if loading == nil {
+		if ks.now().Sub(ks.loadedAt) < ks.interval {
+			ks.mu.Unlock()
+			return nil, fmt.Errorf("JWKS does not contain key %q", kid)
+		}
+		loading = make(chan struct{})
+		ks.loading = loading
+		ks.mu.Unlock()
+
+		err := ks.refresh(ctx)
+		ks.mu.Lock()
+		ks.loading = nil
+		close(loading)
+		ks.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ks.mu.Unlock()
+		select {
+		case <-loading:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if key := ks.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("JWKS does not contain key %q", kid)
+}
+
+// find returns the public signing key with the id kid, or the only signing key of the set if kid is empty. It is called with mu held
+func (ks *jwks) find(kid string) *jose.JSONWebKey {
+	if ks.set == nil {
+		return nil
+	}
+	var found []jose.JSONWebKey
+	for _, k := range ks.set.Keys {
+		if (kid == "" || k.KeyID == kid) && k.IsPublic() && k.Valid() && (k.Use == "" || k.Use == "sig") {
+			found = append(found, k)
+		}
+	}
+	if len(found) != 1 {
+		return nil
+	}
+	return &found[0]
+}
+
+func readJWKS(r io.Reader) (*jose.JSONWebKeySet, error) {
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(io.LimitReader(r, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("could not decode JWKS: %w", err)
+	}
+	return &set, nil
+}
+
+func fetchJWKS(ctx context.Context, client *http.Client, url string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching JWKS from %s: %s", url, res.Status)
+	}
+	return readJWKS(res.Body)
+}
@@ -0,0 +1,350 @@
+package http_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+type testKey struct {
+	id  string
+	alg jose.SignatureAlgorithm
+	key any
+}
+
+func (k testKey) public() jose.JSONWebKey {
+	jwk := jose.JSONWebKey{Key: k.key, KeyID: k.id, Algorithm: string(k.alg), Use: "sig"}
+	return jwk.Public()
+}
+
+func (k testKey) sign(t *testing.T, claims any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: k.alg, Key: jose.JSONWebKey{Key: k.key, KeyID: k.id}}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func newTestKeys(t *testing.T) (testKey, testKey) {
+	t.Helper()
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKey{id: "ec", alg: jose.ES256, key: ec}, testKey{id: "rsa", alg: jose.RS256, key: rs}
+}
+
+type testClaims struct {
+	jwt.Claims
+	Email  string   `json:"email,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+func TestJWT(t *testing.T) {
+	ec, rs := newTestKeys(t)
+	b, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{ec.public(), rs.public()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			JWT: &realmhttp.JWTConfig{
+				JWKSFile:      jwksFile,
+				Issuer:        "https://sso.example.com",
+				Audiences:     []string{"realm"},
+				IdentityClaim: "email",
+				GroupsClaim:   "groups",
+			},
+			Policies:      testPolicies,
+			GroupPolicies: map[string][]string{"payments-team": {"payments"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() testClaims {
+		return testClaims{
+			Claims: jwt.Claims{
+				Issuer:   "https://sso.example.com",
+				Subject:  "1234",
+				Audience: jwt.Audience{"realm", "other"},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+			Email:  "alice@example.com",
+			Groups: []string{"payments-team"},
+		}
+	}
+	with := func(modify func(c *testClaims)) testClaims {
+		c := valid()
+		modify(&c)
+		return c
+	}
+	other, _ := newTestKeys(t)
+
+	tests := []struct {
+		name   string
+		token  string
+		path   string
+		status int
+	}{
+		{"ec key", ec.sign(t, valid()), "payments", http.StatusCreated},
+		{"rsa key", rs.sign(t, valid()), "payments/eu", http.StatusCreated},
+		{"groups grant policies", ec.sign(t, valid()), "billing", http.StatusForbidden},
+		{"without groups", ec.sign(t, with(func(c *testClaims) { c.Groups = nil })), "payments", http.StatusForbidden},
+		{"wrong issuer", ec.sign(t, with(func(c *testClaims) { c.Issuer = "https://evil.example.com" })), "payments", http.StatusUnauthorized},
+		{"wrong audience", ec.sign(t, with(func(c *testClaims) { c.Audience = jwt.Audience{"other"} })), "payments", http.StatusUnauthorized},
+		{"expired", ec.sign(t, with(func(c *testClaims) { c.Expiry = jwt.NewNumericDate(time.Now().Add(-time.Hour)) })), "payments", http.StatusUnauthorized},
+		{"without expiry", ec.sign(t, with(func(c *testClaims) { c.Expiry = nil })), "payments", http.StatusUnauthorized},
+		{"without identity claim", ec.sign(t, with(func(c *testClaims) { c.Email = "" })), "payments", http.StatusUnauthorized},
+		{"signed by another key with the same id", other.sign(t, valid()), "payments", http.StatusUnauthorized},
+		{"signed by an unknown key", testKey{id: "unknown", alg: jose.ES256, key: other.key}.sign(t, valid()), "payments", http.StatusUnauthorized},
+		{"symmetric algorithm", testKey{id: "ec", alg: jose.HS256, key: []byte("secretsecretsecretsecretsecretse")}.sign(t, valid()), "payments", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chambers/"+tt.path, strings.NewReader(`{"rules":{}}`))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestJWTFromURL(t *testing.T) {
+	ec, rs := newTestKeys(t)
+	var mu sync.Mutex
+	keys := []jose.JSONWebKey{ec.public()}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: keys})
+	}))
+	defer jwksServer.Close()
+
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			JWT: &realmhttp.JWTConfig{
+				JWKSURL:         jwksServer.URL,
+				Issuer:          "https://sso.example.com",
+				Audiences:       []string{"realm"},
+				RefreshInterval: time.Nanosecond,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.Claims{Issuer: "https://sso.example.com", Subject: "alice", Audience: jwt.Audience{"realm"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	post := func(token string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/v1/chambers/", strings.NewReader(`{"rules":{}}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if status := post(ec.sign(t, claims)); status != http.StatusCreated {
+		t.Errorf("expected status %d but returned %d", http.StatusCreated, status)
+	}
+	if status := post(rs.sign(t, claims)); status != http.StatusUnauthorized {
+		t.Errorf("expected status %d before the key is published but returned %d", http.StatusUnauthorized, status)
+	}
+
+	// keys rotated by the provider are loaded when a token is signed with an unknown key
+	mu.Lock()
+	keys = append(keys, rs.public())
+	mu.Unlock()
+	if status := post(rs.sign(t, claims)); status != http.StatusCreated {
+		t.Errorf("expected status %d after the key is published but returned %d", http.StatusCreated, status)
+	}
+}
+
+func TestJWTWhileLoadingKeys(t *testing.T) {
+	ec, rs := newTestKeys(t)
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	var loads atomic.Int32
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the key set is loaded at startup and the next load hangs until it is released
+		if loads.Add(1) == 2 {
+			close(requested)
+			<-release
+		}
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{ec.public(), rs.public()}})
+	}))
+	defer jwksServer.Close()
+	var releaseOnce sync.Once
+	releaseLoad := func() { releaseOnce.Do(func() { close(release) }) }
+	defer releaseLoad()
+
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			JWT: &realmhttp.JWTConfig{
+				JWKSURL:         jwksServer.URL,
+				Issuer:          "https://sso.example.com",
+				Audiences:       []string{"realm"},
+				RefreshInterval: time.Nanosecond,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.Claims{Issuer: "https://sso.example.com", Subject: "alice", Audience: jwt.Audience{"realm"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	post := func(token string) <-chan int {
+		status := make(chan int, 1)
+		go func() {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chambers/", strings.NewReader(`{"rules":{}}`))
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			status <- rec.Code
+		}()
+		return status
+	}
+
+	unknown := (testKey{id: "unknown", alg: ec.alg, key: ec.key}).sign(t, claims)
+	pending := post(unknown)
+	<-requested
+
+	// tokens signed with a known key are verified while the key set is loaded
+	select {
+	case status := <-post(rs.sign(t, claims)):
+		if status != http.StatusCreated {
+			t.Errorf("expected status %d but returned %d", http.StatusCreated, status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verifying a token signed with a known key waited for the key set to load")
+	}
+
+	releaseLoad()
+	if status := <-pending; status != http.StatusUnauthorized {
+		t.Errorf("expected status %d for an unknown key but returned %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestJWTFromUnavailableURL(t *testing.T) {
+	ec, _ := newTestKeys(t)
+	var mu sync.Mutex
+	available := false
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{ec.public()}})
+	}))
+	defer jwksServer.Close()
+
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the key set cannot be loaded at startup and the refresh interval is not shortened
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			JWT: &realmhttp.JWTConfig{
+				JWKSURL:   jwksServer.URL,
+				Issuer:    "https://sso.example.com",
+				Audiences: []string{"realm"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := jwt.Claims{Issuer: "https://sso.example.com", Subject: "alice", Audience: jwt.Audience{"realm"}, Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chambers/", strings.NewReader(`{"rules":{}}`))
+	req.Header.Set("Authorization", "Bearer "+ec.sign(t, claims))
+
+	mu.Lock()
+	available = true
+	mu.Unlock()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("expected status %d once the key set is available but returned %d", http.StatusCreated, rec.Code)
+	}
+}
+
+func TestJWTConfigErrors(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		config realmhttp.JWTConfig
+	}{
+		{"no key set", realmhttp.JWTConfig{Issuer: "iss", Audiences: []string{"realm"}}},
+		{"file and url", realmhttp.JWTConfig{JWKSFile: "jwks.json", JWKSURL: "https://sso.example.com/jwks", Issuer: "iss", Audiences: []string{"realm"}}},
+		{"missing file", realmhttp.JWTConfig{JWKSFile: filepath.Join(t.TempDir(), "missing.json"), Issuer: "iss", Audiences: []string{"realm"}}},
+		{"no issuer", realmhttp.JWTConfig{JWKSURL: "https://sso.example.com/jwks", Audiences: []string{"realm"}}},
+		{"no audience", realmhttp.JWTConfig{JWKSURL: "https://sso.example.com/jwks", Issuer: "iss"}},
+		{"symmetric algorithm", realmhttp.JWTConfig{JWKSURL: "https://sso.example.com/jwks", Issuer: "iss", Audiences: []string{"realm"}, Algorithms: []string{"HS256"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg, Auth: realmhttp.AuthConfig{JWT: &tt.config}}); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
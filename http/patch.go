@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/steviebps/realm/helper/jsonpatch"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/utils"
+)
+
+// maxPatchSize limits the size of JSON Merge Patch and JSON Patch documents
+const maxPatchSize = 1 << 20
+
+// acceptPatch lists the media types PATCH requests to chambers support
+var acceptPatch = strings.Join([]string{"application/json", jsonpatch.MergePatchMediaType, jsonpatch.PatchMediaType}, ", ")
+
+// patchFunc returns the patched chamber from the JSON of the current chamber
+type patchFunc func(current []byte) (*realm.Chamber, error)
+
+// readPatch reads the body of a PATCH request according to its media type:
+// a chamber whose rules are added or replaced for application/json, which is the default,
+// a JSON Merge Patch (RFC 7396) where null removes a rule, or a JSON Patch (RFC 6902) for targeted edits such as a single override
+func readPatch(w http.ResponseWriter, r *http.Request) (patchFunc, error) {
+	mediaType := "application/json"
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(ct); err != nil {
+			return nil, &requestError{http.StatusBadRequest, fmt.Sprintf("invalid Content-Type %q", ct)}
+		}
+	}
+
+	switch mediaType {
+	case "application/json":
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, http.StatusText(http.StatusBadRequest)}
+		}
+		// ReadInterfaceWith accepts an empty body, which would patch nothing
+		if len(bytes.TrimSpace(body)) == 0 {
+			return nil, &requestError{http.StatusBadRequest, "request body must not be empty"}
+		}
+		var patchChamber realm.Chamber
+		// ensure data is in correct format
+		if err := utils.ReadInterfaceWith(bytes.NewReader(body), &patchChamber); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, &requestError{http.StatusBadRequest, "request body must not be empty"}
+			}
+			return nil, &requestError{http.StatusBadRequest, http.StatusText(http.StatusBadRequest)}
+		}
+		return func(current []byte) (*realm.Chamber, error) {
+			var c realm.Chamber
+			if err := json.Unmarshal(current, &c); err != nil {
+				return nil, fmt.Errorf("could not unmarshal current chamber while patching: %w", err)
+			}
+			c.OverwriteFrom(&patchChamber)
+			return &c, nil
+		}, nil
+
+	case jsonpatch.MergePatchMediaType, jsonpatch.PatchMediaType:
+		patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+		if err != nil {
+			return nil, &requestError{http.StatusBadRequest, http.StatusText(http.StatusBadRequest)}
+		}
+		if len(patch) == 0 {
+			return nil, &requestError{http.StatusBadRequest, "request body must not be empty"}
+		}
+
+		apply := jsonpatch.Apply
+		if mediaType == jsonpatch.MergePatchMediaType {
+			apply = jsonpatch.MergePatch
+		}
+		return func(current []byte) (*realm.Chamber, error) {
+			patched, err := apply(current, patch)
+			if err != nil {
+				if errors.Is(err, jsonpatch.ErrInvalidPatch) {
+					return nil, &requestError{http.StatusBadRequest, err.Error()}
+				}
+				return nil, &requestError{http.StatusConflict, fmt.Sprintf("could not apply patch: %s", err.Error())}
+			}
+			return validateChamber(patched)
+		}, nil
+	}
+
+	w.Header().Set("Accept-Patch", acceptPatch)
+	return nil, &requestError{http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported media type %q, must be one of %s", mediaType, acceptPatch)}
+}
+
+// validateChamber returns the chamber of a patched document if it is valid, with the same validation as a chamber that is put
+func validateChamber(b []byte) (*realm.Chamber, error) {
+	invalid := func(msg string) error {
+		return &requestError{http.StatusUnprocessableEntity, "patched chamber is invalid: " + msg}
+	}
+
+	if !bytes.HasPrefix(b, []byte("{")) {
+		return nil, invalid("must be an object")
+	}
+	var c realm.Chamber
+	if err := utils.ReadInterfaceWith(bytes.NewReader(b), &c); err != nil {
+		return nil, invalid(err.Error())
+	}
+	for key, rule := range c.Rules {
+		if rule == nil || rule.Rule == nil {
+			return nil, invalid(fmt.Sprintf("rule %q must not be null", key))
+		}
+		for _, override := range rule.Overrides {
+			if override == nil {
+				return nil, invalid(fmt.Sprintf("overrides of rule %q must not be null", key))
+			}
+		}
+	}
+	return &c, nil
+}
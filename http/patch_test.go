@@ -0,0 +1,89 @@
+package http_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/realmtest"
+)
+
+func TestPatch(t *testing.T) {
+	const initial = `{"rules":{
+		"enabled":{"type":"boolean","value":true},
+		"message":{"type":"string","value":"hello","overrides":[{"type":"string","value":"v2","minimumVersion":"v2.0.0","maximumVersion":"v2.9.9"}]}
+	}}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		status      int
+		expected    string
+	}{
+		{"chamber", "application/json", `{"rules":{"enabled":{"type":"boolean","value":false}}}`, http.StatusNoContent,
+			`{"rules":{"enabled":{"type":"boolean","value":false},"message":{"type":"string","value":"hello","overrides":[{"type":"string","value":"v2","minimumVersion":"v2.0.0","maximumVersion":"v2.9.9"}]}}}`},
+		{"merge patch removes rule", "application/merge-patch+json; charset=utf-8", `{"rules":{"enabled":null,"port":{"type":"number","value":8080}}}`, http.StatusNoContent,
+			`{"rules":{"port":{"type":"number","value":8080},"message":{"type":"string","value":"hello","overrides":[{"type":"string","value":"v2","minimumVersion":"v2.0.0","maximumVersion":"v2.9.9"}]}}}`},
+		{"json patch edits override", "application/json-patch+json", `[{"op":"test","path":"/rules/message/overrides/0/value","value":"v2"},{"op":"replace","path":"/rules/message/overrides/0/value","value":"two"}]`, http.StatusNoContent,
+			`{"rules":{"enabled":{"type":"boolean","value":true},"message":{"type":"string","value":"hello","overrides":[{"type":"string","value":"two","minimumVersion":"v2.0.0","maximumVersion":"v2.9.9"}]}}}`},
+		{"json patch removes rule", "application/json-patch+json", `[{"op":"remove","path":"/rules/message"}]`, http.StatusNoContent,
+			`{"rules":{"enabled":{"type":"boolean","value":true}}}`},
+		{"invalid rule value", "application/merge-patch+json", `{"rules":{"enabled":{"value":"yes"}}}`, http.StatusUnprocessableEntity, initial},
+		{"null rule", "application/json-patch+json", `[{"op":"replace","path":"/rules/enabled","value":null}]`, http.StatusUnprocessableEntity, initial},
+		{"overlapping overrides", "application/json-patch+json", `[{"op":"add","path":"/rules/message/overrides/-","value":{"type":"string","value":"x","minimumVersion":"v2.5.0","maximumVersion":"v3.0.0"}}]`, http.StatusUnprocessableEntity, initial},
+		{"not an object", "application/merge-patch+json", `null`, http.StatusUnprocessableEntity, initial},
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/rules/enabled/value","value":false},{"op":"remove","path":"/rules/enabled"}]`, http.StatusConflict, initial},
+		{"malformed json patch", "application/json-patch+json", `{"op":"remove"}`, http.StatusBadRequest, initial},
+		{"unsupported media type", "text/plain", `enabled=false`, http.StatusUnsupportedMediaType, initial},
+		{"empty chamber", "application/json", ``, http.StatusBadRequest, initial},
+		{"empty merge patch", "application/merge-patch+json", ``, http.StatusBadRequest, initial},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c realm.Chamber
+			if err := json.Unmarshal([]byte(initial), &c); err != nil {
+				t.Fatal(err)
+			}
+			srv := realmtest.NewServer(t, map[string]*realm.Chamber{"/app/": &c})
+
+			req, _ := http.NewRequest(http.MethodPatch, srv.URL+"/v1/chambers/app", strings.NewReader(tt.patch))
+			req.Header.Set("Content-Type", tt.contentType)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode != tt.status {
+				t.Fatalf("expected status %d but returned %d: %s", tt.status, res.StatusCode, body)
+			}
+			if tt.patch == "" && !strings.Contains(string(body), "request body must not be empty") {
+				t.Errorf("expected an empty body error but returned %s", body)
+			}
+			if tt.status == http.StatusUnsupportedMediaType && res.Header.Get("Accept-Patch") == "" {
+				t.Error("expected Accept-Patch to be set")
+			}
+
+			res, err = http.Get(srv.URL + "/v1/chambers/app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			var got api.HTTPErrorAndDataResponse
+			if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			var gotChamber, expectedChamber realm.Chamber
+			json.Unmarshal(got.Data, &gotChamber)
+			json.Unmarshal([]byte(tt.expected), &expectedChamber)
+			if gotChamber.Revision() != expectedChamber.Revision() {
+				t.Errorf("expected chamber %s but returned %s", tt.expected, got.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/steviebps/realm/utils"
+)
+
+// Capability is an operation that a policy grants on chamber paths
+type Capability string
+
+const (
+	ReadCapability   Capability = "read"
+	ListCapability   Capability = "list"
+	WriteCapability  Capability = "write"
+	DeleteCapability Capability = "delete"
+	// DenyCapability revokes every capability on the path, even the ones granted by other policies
+	DenyCapability Capability = "deny"
+)
+
+var capabilityBits = map[Capability]capabilities{
+	ReadCapability:   1 << 0,
+	ListCapability:   1 << 1,
+	WriteCapability:  1 << 2,
+	DeleteCapability: 1 << 3,
+	DenyCapability:   1 << 4,
+}
+
+type capabilities uint8
+
+func (c capabilities) has(capability Capability) bool {
+	return c&capabilityBits[DenyCapability] == 0 && c&capabilityBits[capability] != 0
+}
+
+// Policy grants capabilities on chamber paths, e.g. to let a team write under /payments/ while reading its parents
+type Policy struct {
+	Name  string       `json:"name"`
+	Paths []PathPolicy `json:"paths"`
+}
+
+// PathPolicy grants capabilities on the chamber paths matching Path.
+// A trailing * matches any suffix, e.g. /payments/* matches /payments/ and every chamber below it, and + matches a single path segment, e.g. /teams/+/
+type PathPolicy struct {
+	Path         string       `json:"path"`
+	Capabilities []Capability `json:"capabilities"`
+}
+
+type pathRule struct {
+	segments []string
+	prefix   bool
+	caps     capabilities
+}
+
+// ACL evaluates the capabilities that a set of policies grants on chamber paths.
+// The capabilities of every path rule matching a path are combined unless one of them is deny
+type ACL struct {
+	policies map[string][]pathRule
+}
+
+// NewACL returns an ACL of the policies
+func NewACL(policies []Policy) (*ACL, error) {
+	acl := &ACL{policies: make(map[string][]pathRule, len(policies))}
+	for _, p := range policies {
+		if p.Name == "" {
+			return nil, errors.New("policies must have a name")
+		}
+		if _, exists := acl.policies[p.Name]; exists {
+			return nil, fmt.Errorf("policy %q is defined more than once", p.Name)
+		}
+
+		rules := make([]pathRule, 0, len(p.Paths))
+		for _, pp := range p.Paths {
+			rule, err := newPathRule(pp)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q of policy %q: %w", pp.Path, p.Name, err)
+			}
+			rules = append(rules, rule)
+		}
+		acl.policies[p.Name] = rules
+	}
+	return acl, nil
+}
+
+func newPathRule(pp PathPolicy) (pathRule, error) {
+	if len(pp.Capabilities) == 0 {
+		return pathRule{}, errors.New("capabilities must not be empty")
+	}
+	var rule pathRule
+	for _, c := range pp.Capabilities {
+		bit, ok := capabilityBits[c]
+		if !ok {
+			return pathRule{}, fmt.Errorf("unknown capability %q", c)
+		}
+		rule.caps |= bit
+	}
+
+	p := "/" + strings.TrimPrefix(pp.Path, "/")
+	p, rule.prefix = strings.CutSuffix(p, "*")
+	if strings.Contains(p, "*") {
+		return pathRule{}, errors.New("* is only allowed at the end of a path")
+	}
+	if !rule.prefix {
+		p = utils.EnsureTrailingSlash(p)
+	}
+	rule.segments = strings.Split(p, "/")
+	return rule, nil
+}
+
+// matches reports whether the chamber path p matches the rule
+func (r pathRule) matches(p string) bool {
+	segments := strings.Split(p, "/")
+	if len(segments) < len(r.segments) || (!r.prefix && len(segments) != len(r.segments)) {
+		return false
+	}
+	last := len(r.segments) - 1
+	for i, s := range r.segments {
+		switch {
+		case s == "+" && segments[i] != "":
+		case r.prefix && i == last:
+			return strings.HasPrefix(segments[i], s)
+		case s != segments[i]:
+			return false
+		}
+	}
+	return true
+}
+
+// matchesBelow reports whether the rule matches any chamber path under the chamber path p
+func (r pathRule) matchesBelow(p string) bool {
+	segments := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	if len(segments) >= len(r.segments) {
+		return r.matches(p)
+	}
+	for i, s := range segments {
+		if s != r.segments[i] && (r.segments[i] != "+" || s == "") {
+			return false
+		}
+	}
+	return true
+}
+
+// granted returns the capabilities that the named policies grant on the chamber path p
+func (acl *ACL) granted(names []string, p string) capabilities {
+	var caps capabilities
+	for _, name := range names {
+		for _, rule := range acl.policies[name] {
+			if rule.matches(p) {
+				caps |= rule.caps
+			}
+		}
+	}
+	return caps
+}
+
+// Allowed reports whether the named policies grant capability c on the chamber path p
+func (acl *ACL) Allowed(names []string, p string, c Capability) bool {
+	return acl.granted(names, utils.EnsureTrailingSlash(p)).has(c)
+}
+
+// Visible reports whether the named policies grant any capability on the chamber path p or on a chamber below it,
+// so that callers can list their way down to the chambers they have access to
+func (acl *ACL) Visible(names []string, p string) bool {
+	p = utils.EnsureTrailingSlash(p)
+	caps := acl.granted(names, p)
+	if caps&capabilityBits[DenyCapability] != 0 {
+		return false
+	}
+	if caps != 0 {
+		return true
+	}
+	for _, name := range names {
+		for _, rule := range acl.policies[name] {
+			if rule.caps&capabilityBits[DenyCapability] == 0 && rule.matchesBelow(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (acl *ACL) hasPolicy(name string) bool {
+	_, exists := acl.policies[name]
+	return exists
+}
+
+// authorizer decides which chamber operations the caller of a request is allowed to perform
+type authorizer struct {
+	acl *ACL
+	// anonymous are the policies of requests without a token
+	anonymous []string
+}
+
+func newAuthorizer(c AuthConfig) (*authorizer, error) {
+	acl, err := NewACL(c.Policies)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string][]string{"anonymous requests": c.AnonymousPolicies}
+	for _, t := range c.Tokens {
+		referenced["token "+t.Name] = t.Policies
+	}
+	for name, policies := range c.IdentityPolicies {
+		referenced["identity "+name] = policies
+	}
+	for group, policies := range c.GroupPolicies {
+		referenced["group "+group] = policies
+	}
+	for by, policies := range referenced {
+		for _, p := range policies {
+			if !acl.hasPolicy(p) {
+				return nil, fmt.Errorf("policy %q of %s is not defined", p, by)
+			}
+		}
+	}
+	if len(c.Policies) == 0 {
+		return nil, nil
+	}
+	return &authorizer{acl: acl, anonymous: c.AnonymousPolicies}, nil
+}
+
+// policies returns the names of the policies of the caller stored in ctx
+func (a *authorizer) policies(ctx context.Context) []string {
+	if id, ok := IdentityFrom(ctx); ok {
+		return id.Policies
+	}
+	return a.anonymous
+}
+
+// allowed reports whether the caller stored in ctx has capability c on the chamber path p. Every operation is allowed when a is nil
+func (a *authorizer) allowed(ctx context.Context, p string, c Capability) bool {
+	return a == nil || a.acl.Allowed(a.policies(ctx), p, c)
+}
+
+// visible reports whether the caller stored in ctx may see the chamber path p when listing its parent
+func (a *authorizer) visible(ctx context.Context, p string) bool {
+	return a == nil || a.acl.Visible(a.policies(ctx), p)
+}
+
+// operationCapability returns the capability required to perform op. Changing or removing a single rule writes its chamber
+func operationCapability(op Operation, ruleKey string) Capability {
+	switch op {
+	case GetOperation:
+		return ReadCapability
+	case ListOperation:
+		return ListCapability
+	case DeleteOperation:
+		if ruleKey != "" {
+			return WriteCapability
+		}
+		return DeleteCapability
+	}
+	return WriteCapability
+}
+
+// handleUnauthorized responds to a request that is not allowed to perform an operation, asking anonymous callers to authenticate
+func handleUnauthorized(ctx context.Context, w http.ResponseWriter, msg string) {
+	if _, ok := IdentityFrom(ctx); ok {
+		handleError(ctx, w, http.StatusForbidden, createResponseWithErrors(nil, []string{msg}))
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="realm"`)
+	handleError(ctx, w, http.StatusUnauthorized, createResponseWithErrors(nil, []string{msg}))
+}
@@ -0,0 +1,258 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+var testPolicies = []realmhttp.Policy{
+	{Name: "reader", Paths: []realmhttp.PathPolicy{
+		{Path: "/", Capabilities: []realmhttp.Capability{realmhttp.ReadCapability, realmhttp.ListCapability}},
+	}},
+	{Name: "payments", Paths: []realmhttp.PathPolicy{
+		{Path: "/payments/*", Capabilities: []realmhttp.Capability{realmhttp.ReadCapability, realmhttp.ListCapability, realmhttp.WriteCapability, realmhttp.DeleteCapability}},
+		{Path: "/payments/locked/", Capabilities: []realmhttp.Capability{realmhttp.DenyCapability}},
+	}},
+	{Name: "teams", Paths: []realmhttp.PathPolicy{
+		{Path: "teams/+/config", Capabilities: []realmhttp.Capability{realmhttp.WriteCapability}},
+	}},
+}
+
+func TestACLAllowed(t *testing.T) {
+	acl, err := realmhttp.NewACL(testPolicies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		policies   []string
+		path       string
+		capability realmhttp.Capability
+		allowed    bool
+	}{
+		{"exact path", []string{"reader"}, "/", realmhttp.ReadCapability, true},
+		{"exact path does not match children", []string{"reader"}, "/payments/", realmhttp.ReadCapability, false},
+		{"capability not granted", []string{"reader"}, "/", realmhttp.WriteCapability, false},
+		{"glob matches its root", []string{"payments"}, "/payments/", realmhttp.WriteCapability, true},
+		{"glob matches descendants", []string{"payments"}, "/payments/eu/checkout/", realmhttp.DeleteCapability, true},
+		{"glob does not match siblings", []string{"payments"}, "/billing/", realmhttp.ReadCapability, false},
+		{"path without trailing slash", []string{"payments"}, "/payments/eu", realmhttp.ReadCapability, true},
+		{"deny overrides glob", []string{"payments"}, "/payments/locked/", realmhttp.ReadCapability, false},
+		{"deny overrides other policies", []string{"reader", "payments"}, "/payments/locked/", realmhttp.ReadCapability, false},
+		{"deny does not apply to descendants", []string{"payments"}, "/payments/locked/eu/", realmhttp.ReadCapability, true},
+		{"policies are combined", []string{"reader", "payments"}, "/", realmhttp.ListCapability, true},
+		{"single segment wildcard", []string{"teams"}, "/teams/a/config/", realmhttp.WriteCapability, true},
+		{"single segment wildcard does not match several segments", []string{"teams"}, "/teams/a/b/config/", realmhttp.WriteCapability, false},
+		{"unknown policy", []string{"unknown"}, "/", realmhttp.ReadCapability, false},
+		{"no policies", nil, "/", realmhttp.ReadCapability, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := acl.Allowed(tt.policies, tt.path, tt.capability); allowed != tt.allowed {
+				t.Errorf("expected %t but returned %t", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestACLVisible(t *testing.T) {
+	acl, err := realmhttp.NewACL(testPolicies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		policies []string
+		path     string
+		visible  bool
+	}{
+		{"path with capabilities", []string{"payments"}, "/payments/eu/", true},
+		{"parent of a glob", []string{"payments"}, "/", true},
+		{"parent of a wildcard", []string{"teams"}, "/teams/a/", true},
+		{"unrelated path", []string{"payments"}, "/billing/", false},
+		{"denied path", []string{"payments"}, "/payments/locked/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if visible := acl.Visible(tt.policies, tt.path); visible != tt.visible {
+				t.Errorf("expected %t but returned %t", tt.visible, visible)
+			}
+		})
+	}
+}
+
+func TestNewACLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []realmhttp.Policy
+	}{
+		{"missing name", []realmhttp.Policy{{Paths: []realmhttp.PathPolicy{{Path: "/", Capabilities: []realmhttp.Capability{realmhttp.ReadCapability}}}}}},
+		{"duplicate name", []realmhttp.Policy{{Name: "a"}, {Name: "a"}}},
+		{"unknown capability", []realmhttp.Policy{{Name: "a", Paths: []realmhttp.PathPolicy{{Path: "/", Capabilities: []realmhttp.Capability{"sudo"}}}}}},
+		{"no capabilities", []realmhttp.Policy{{Name: "a", Paths: []realmhttp.PathPolicy{{Path: "/"}}}}},
+		{"glob in the middle", []realmhttp.Policy{{Name: "a", Paths: []realmhttp.PathPolicy{{Path: "/*/a/", Capabilities: []realmhttp.Capability{realmhttp.ReadCapability}}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := realmhttp.NewACL(tt.policies); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestChamberPolicies(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/", "/payments/", "/billing/"} {
+		if err := stg.Put(context.Background(), storage.StorageEntry{Key: p, Value: []byte(`{"rules":{}}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage: stg,
+		Auth: realmhttp.AuthConfig{
+			Tokens:            []realmhttp.StaticToken{{Name: "payments-ci", Hash: realmhttp.HashToken("payments-token"), Policies: []string{"payments"}}},
+			Policies:          testPolicies,
+			IdentityPolicies:  map[string][]string{"payments-ci": {"reader"}},
+			AnonymousPolicies: []string{"reader"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method string, path string, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, "/v1/chambers/"+path, strings.NewReader(`{"rules":{}}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{"anonymous read of root", http.MethodGet, "", "", http.StatusOK},
+		{"anonymous read outside policies", http.MethodGet, "billing", "", http.StatusUnauthorized},
+		{"read of shared parent", http.MethodGet, "", "payments-token", http.StatusOK},
+		{"write own subtree", http.MethodPost, "payments/eu", "payments-token", http.StatusCreated},
+		{"write chamber with rules segment in own subtree", http.MethodPut, "payments/rules/enabled", "payments-token", http.StatusCreated},
+		{"write other subtree", http.MethodPost, "billing", "payments-token", http.StatusForbidden},
+		{"write shared parent", http.MethodPatch, "", "payments-token", http.StatusForbidden},
+		{"delete own subtree", http.MethodDelete, "payments/eu", "payments-token", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.method, tt.path, tt.token); rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("list is filtered", func(t *testing.T) {
+		rec := do(http.MethodGet, "?list=true", "payments-token")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d but returned %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var res api.HTTPErrorAndDataResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		if err := json.Unmarshal(res.Data, &names); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(names, []string{"payments"}) {
+			t.Errorf("expected only payments to be listed but returned %v", names)
+		}
+	})
+}
+
+func TestUsagePolicies(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stg.Put(context.Background(), storage.StorageEntry{Key: "/billing/", Value: []byte(`{"rules":{"enabled":{"type":"boolean","value":true}}}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	usageStg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{
+		Storage:      stg,
+		UsageStorage: usageStg,
+		Auth: realmhttp.AuthConfig{
+			Tokens:   []realmhttp.StaticToken{{Name: "payments-ci", Hash: realmhttp.HashToken("payments-token"), Policies: []string{"payments"}}},
+			Policies: testPolicies,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"read usage of own subtree", http.MethodGet, "/v1/usage/payments/eu", "", http.StatusOK},
+		{"read usage of other subtree", http.MethodGet, "/v1/usage/billing", "", http.StatusForbidden},
+		{"report usage of own subtree", http.MethodPost, "/v1/usage", `{"application":"checkout","chambers":{"/payments/":{"enabled":1}}}`, http.StatusNoContent},
+		{"report usage of other subtree", http.MethodPost, "/v1/usage", `{"application":"checkout","chambers":{"/payments/":{"enabled":1},"/billing/":{"enabled":1}}}`, http.StatusForbidden},
+		{"report usage of denied path", http.MethodPost, "/v1/usage", `{"application":"checkout","chambers":{"payments/locked":{"enabled":1}}}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer payments-token")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	// rejected reports are not partially recorded
+	if _, err := usageStg.Get(context.Background(), "/billing/"); err == nil {
+		t.Error("expected the usage of /billing/ not to be recorded")
+	}
+	entry, err := usageStg.Get(context.Background(), "/payments/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records map[string]api.RuleUsage
+	if err := json.Unmarshal(entry.Value, &records); err != nil {
+		t.Fatal(err)
+	}
+	if records["enabled"].Count != 1 {
+		t.Errorf("expected only the accepted report to be recorded but returned %+v", records)
+	}
+}
@@ -63,7 +63,8 @@ const Content = () => {
           'Content-Type': 'application/json',
         },
       }).then((res) => {
-        return res.json();
+        const etag = res.headers.get('ETag') ?? undefined;
+        return res.json().then((body: ChamberResponse) => ({ ...body, etag }));
       });
     }
   );
@@ -46,6 +46,7 @@ export const RuleInput = ({ ruleName, rule }: { ruleName: string; rule: Rule })
         mode: 'same-origin',
         headers: {
           'Content-Type': 'application/json',
+          ...(res.etag ? { 'If-Match': res.etag } : {}),
         },
       })
         .then((res) => {
@@ -82,6 +83,7 @@ export const RuleInput = ({ ruleName, rule }: { ruleName: string; rule: Rule })
         mode: 'same-origin',
         headers: {
           'Content-Type': 'application/json',
+          ...(res.etag ? { 'If-Match': res.etag } : {}),
         },
       }).then((res) => {
         return res.json();
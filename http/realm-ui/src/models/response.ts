@@ -6,6 +6,8 @@ export type ChamberResponse = {
   data?: {
     rules: Rules;
   };
+  // etag is the revision of the chamber that writes must match to not overwrite concurrent changes
+  etag?: string;
 };
 
 export type Rules = Record<string, Rule>;
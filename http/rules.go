@@ -0,0 +1,173 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/steviebps/realm/helper/logging"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestError aborts a storage update with the status to respond with instead of an internal server error
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (re *requestError) Error() string {
+	return re.msg
+}
+
+// errorStatus returns the status to respond with for an error of a storage operation
+func errorStatus(err error) int {
+	var reqErr *requestError
+	var nfError *storage.NotFoundError
+	switch {
+	case errors.As(err, &reqErr):
+		return reqErr.status
+	case errors.As(err, &nfError):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// handleRule gets, puts or deletes a single rule of a chamber on /v1/rules/{path}?key={key}.
+// Puts and deletes are applied to the chamber with a single storage update so that concurrent changes to other rules are not lost
+func handleRule(w http.ResponseWriter, req *AgentRequest, strg storage.Storage, rec changeRecorder) {
+	ctx := req.Context()
+	logger := logging.Ctx(ctx)
+	errorLog := logger.ErrorCtx(ctx).Str("method", req.Method).Str("path", req.URL.Path)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("realm.server.ruleKey", req.RuleKey))
+
+	fail := func(err error) {
+		span.SetStatus(codes.Error, err.Error())
+		errorLog.Msg(err.Error())
+
+		handleError(ctx, w, errorStatus(err), createResponseWithErrors(nil, []string{err.Error()}))
+	}
+
+	switch req.Operation {
+	case GetOperation:
+		entry, etag, err := getChamber(ctx, strg, req.Path)
+		if err != nil {
+			fail(err)
+			return
+		}
+		chamber, err := unmarshalChamber(req.Path, entry)
+		if err != nil {
+			fail(err)
+			return
+		}
+		rule, ok := chamber.Rules[req.RuleKey]
+		if !ok {
+			fail(&requestError{http.StatusNotFound, fmt.Sprintf("rule %q does not exist in %q", req.RuleKey, req.Path)})
+			return
+		}
+
+		raw, err := json.Marshal(rule)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		handleOk(w, createResponseWithErrors(raw, nil))
+
+	case PutOperation:
+		var rule realm.OverrideableRule
+		// ensure data is in correct format
+		if err := utils.ReadInterfaceWith(req.Body, &rule); err != nil {
+			fail(&requestError{http.StatusBadRequest, fmt.Sprintf("invalid rule %q: %s", req.RuleKey, err.Error())})
+			return
+		}
+		if rule.Rule == nil {
+			fail(&requestError{http.StatusBadRequest, "request body must not be empty"})
+			return
+		}
+
+		var created bool
+		var etag string
+		err := updateChamber(ctx, strg, rec, req, func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+			chamber, err := unmarshalChamber(req.Path, current)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkIfMatch(req.Request, req.Path, current); err != nil {
+				return nil, err
+			}
+			_, exists := chamber.Rules[req.RuleKey]
+			created = !exists
+			chamber.Rules[req.RuleKey] = &rule
+			etag = etagOf(chamber)
+			return marshalChamber(req.Path, chamber)
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		w.Header().Set("ETag", etag)
+
+		if created {
+			handleWithStatus(w, http.StatusCreated, nil)
+			return
+		}
+		handleOk(w, nil)
+
+	case DeleteOperation:
+		var etag string
+		err := updateChamber(ctx, strg, rec, req, func(current *storage.StorageEntry) (*storage.StorageEntry, error) {
+			chamber, err := unmarshalChamber(req.Path, current)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkIfMatch(req.Request, req.Path, current); err != nil {
+				return nil, err
+			}
+			if _, ok := chamber.Rules[req.RuleKey]; !ok {
+				return nil, &requestError{http.StatusNotFound, fmt.Sprintf("rule %q does not exist in %q", req.RuleKey, req.Path)}
+			}
+			delete(chamber.Rules, req.RuleKey)
+			etag = etagOf(chamber)
+			return marshalChamber(req.Path, chamber)
+		})
+		if err != nil {
+			fail(err)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		handleOk(w, nil)
+
+	default:
+		span.SetStatus(codes.Error, "method not allowed")
+		handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+	}
+}
+
+// unmarshalChamber returns the chamber at path from entry, which is nil when the chamber does not exist
+func unmarshalChamber(path string, entry *storage.StorageEntry) (*realm.Chamber, error) {
+	if entry == nil {
+		return nil, &storage.NotFoundError{Key: path}
+	}
+	var chamber realm.Chamber
+	if err := json.Unmarshal(entry.Value, &chamber); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chamber %q: %w", path, err)
+	}
+	return &chamber, nil
+}
+
+func marshalChamber(path string, chamber *realm.Chamber) (*storage.StorageEntry, error) {
+	b, err := json.Marshal(chamber)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal chamber %q: %w", path, err)
+	}
+	return &storage.StorageEntry{Key: path, Value: b}, nil
+}
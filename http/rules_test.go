@@ -0,0 +1,105 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestRules(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a chamber whose path has a rules segment must remain reachable as a chamber
+	seed := map[string]string{
+		"/app/":            `{"rules":{"enabled":{"type":"boolean","value":true}}}`,
+		"/app/rules/beta/": `{"rules":{"flag":{"type":"string","value":"beta"}}}`,
+	}
+	for p, v := range seed {
+		if err := stg.Put(context.Background(), storage.StorageEntry{Key: p, Value: []byte(v)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	chamberAt := func(p string) *realm.Chamber {
+		t.Helper()
+		entry, err := stg.Get(context.Background(), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c realm.Chamber
+		if err := json.Unmarshal(entry.Value, &c); err != nil {
+			t.Fatal(err)
+		}
+		return &c
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"get rule", http.MethodGet, "/v1/rules/app?key=enabled", "", http.StatusOK},
+		{"get missing rule", http.MethodGet, "/v1/rules/app?key=missing", "", http.StatusNotFound},
+		{"get rule of missing chamber", http.MethodGet, "/v1/rules/missing?key=enabled", "", http.StatusNotFound},
+		{"get rule without key", http.MethodGet, "/v1/rules/app", "", http.StatusBadRequest},
+		{"create rule", http.MethodPut, "/v1/rules/app?key=created", `{"type":"string","value":"a"}`, http.StatusCreated},
+		{"replace rule", http.MethodPut, "/v1/rules/app?key=created", `{"type":"string","value":"b"}`, http.StatusNoContent},
+		{"put invalid rule", http.MethodPut, "/v1/rules/app?key=created", `{"type":"boolean","value":"yes"}`, http.StatusBadRequest},
+		{"delete rule", http.MethodDelete, "/v1/rules/app?key=created", "", http.StatusNoContent},
+		{"delete missing rule", http.MethodDelete, "/v1/rules/app?key=created", "", http.StatusNotFound},
+		{"get chamber with rules segment", http.MethodGet, "/v1/chambers/app/rules/beta", "", http.StatusOK},
+		{"get rule of chamber with rules segment", http.MethodGet, "/v1/rules/app/rules/beta?key=flag", "", http.StatusOK},
+		{"put chamber with rules segment", http.MethodPut, "/v1/chambers/app/rules/beta/", `{"rules":{"flag":{"type":"string","value":"gamma"}}}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.method, tt.target, tt.body); rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := do(http.MethodGet, "/v1/rules/app?key=enabled", "")
+	var res api.HTTPErrorAndDataResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var rule realm.OverrideableRule
+	if err := json.Unmarshal(res.Data, &rule); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Value != true {
+		t.Errorf("expected rule value true but returned %v", rule.Value)
+	}
+
+	app := chamberAt("/app/")
+	if _, ok := app.Rules["beta"]; ok || len(app.Rules) != 1 {
+		t.Errorf("expected the chamber with a rules segment not to change /app/ but returned %+v", app.Rules)
+	}
+	if beta := chamberAt("/app/rules/beta/"); beta.Rules["flag"] == nil || beta.Rules["flag"].Value != "gamma" {
+		t.Errorf("expected the chamber with a rules segment to be replaced but returned %+v", beta.Rules)
+	}
+}
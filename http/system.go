@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	"github.com/steviebps/realm/pkg/storage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultVersion is the version reported by /v1/sys/version when HandlerConfig.Version is empty
+const DefaultVersion = "development"
+
+// handleSystem serves the result of fn to GET and HEAD requests, or 503 when fn fails. Other methods are not allowed
+func handleSystem(fn func(ctx context.Context) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			span.SetStatus(codes.Error, "method not allowed")
+			w.Header().Set("Allow", "GET, HEAD")
+			handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+			return
+		}
+
+		v, err := fn(ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			logging.Ctx(ctx).ErrorCtx(ctx).Str("path", r.URL.Path).Msg(err.Error())
+			handleError(ctx, w, http.StatusServiceUnavailable, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+			return
+		}
+		handleOk(w, createResponseWithErrors(raw, nil))
+	})
+}
+
+// handleLiveness reports that the server is able to serve requests
+func handleLiveness() http.Handler {
+	return handleSystem(func(ctx context.Context) (any, error) {
+		return api.HealthStatus{Status: "ok"}, nil
+	})
+}
+
+// handleReadiness reports whether strg can serve a Get of the root chamber. A root chamber that does not exist yet is served as well
+func handleReadiness(strg storage.Storage) http.Handler {
+	return handleSystem(func(ctx context.Context) (any, error) {
+		_, err := strg.Get(ctx, "/")
+		var nfError *storage.NotFoundError
+		if err != nil && !errors.As(err, &nfError) {
+			return nil, fmt.Errorf("storage is not ready: %w", err)
+		}
+		return api.HealthStatus{Status: "ok"}, nil
+	})
+}
+
+// handleVersion reports the version of the server
+func handleVersion(version string) http.Handler {
+	if version == "" {
+		version = DefaultVersion
+	}
+	info := api.VersionInfo{Version: version, GoVersion: runtime.Version()}
+	return handleSystem(func(ctx context.Context) (any, error) {
+		return info, nil
+	})
+}
@@ -0,0 +1,87 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+// unavailableStorage fails every Get as if the backend could not be reached
+type unavailableStorage struct {
+	storage.Storage
+}
+
+func (unavailableStorage) Get(ctx context.Context, key string) (*storage.StorageEntry, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestSystemEndpoints(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := realmhttp.AuthConfig{
+		Tokens: []realmhttp.StaticToken{{Name: "alice", Hash: realmhttp.HashToken("alice-token")}},
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg, Auth: auth, Version: "v1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unavailable, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: unavailableStorage{stg}, Auth: auth})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		method  string
+		target  string
+		token   string
+		status  int
+	}{
+		{"liveness", handler, http.MethodGet, "/v1/sys/health", "", http.StatusOK},
+		{"liveness head", handler, http.MethodHead, "/v1/sys/health", "", http.StatusOK},
+		{"liveness with an invalid token", handler, http.MethodGet, "/v1/sys/health", "wrong-token", http.StatusOK},
+		{"readiness without root chamber", handler, http.MethodGet, "/v1/sys/ready", "", http.StatusOK},
+		{"readiness with unavailable storage", unavailable, http.MethodGet, "/v1/sys/ready", "", http.StatusServiceUnavailable},
+		{"liveness with unavailable storage", unavailable, http.MethodGet, "/v1/sys/health", "", http.StatusOK},
+		{"version", handler, http.MethodGet, "/v1/sys/version", "", http.StatusOK},
+		{"method not allowed", handler, http.MethodPost, "/v1/sys/health", "", http.StatusMethodNotAllowed},
+		{"other endpoints still authenticate", handler, http.MethodPost, "/v1/chambers/app", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/sys/version", nil))
+	var res api.HTTPErrorAndDataResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	var info api.VersionInfo
+	if err := json.Unmarshal(res.Data, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != "v1.2.3" || info.GoVersion == "" {
+		t.Errorf("unexpected version info: %+v", info)
+	}
+}
@@ -0,0 +1,209 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/helper/logging"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxUsageReportSize limits the size of usage reports sent by SDKs
+const maxUsageReportSize = 1 << 20
+
+// usageStore keeps a usage record per rule of every chamber path.
+// The records of a chamber are stored as a single entry keyed by the chamber path
+type usageStore struct {
+	mu   sync.Mutex
+	strg storage.Storage
+}
+
+// usagePath returns the path records are stored at, which matches the logical path of chamber requests
+func usagePath(p string) string {
+	return utils.EnsureTrailingSlash("/" + strings.TrimPrefix(p, "/"))
+}
+
+func (us *usageStore) get(ctx context.Context, p string) (map[string]api.RuleUsage, error) {
+	records := make(map[string]api.RuleUsage)
+	entry, err := us.strg.Get(ctx, usagePath(p))
+	if err != nil {
+		var nfError *storage.NotFoundError
+		if errors.As(err, &nfError) {
+			return records, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(entry.Value, &records); err != nil {
+		return nil, fmt.Errorf("could not unmarshal usage records of %q: %w", p, err)
+	}
+	return records, nil
+}
+
+// ingest adds the counts of the report to the usage records, marking the rules as evaluated at now
+func (us *usageStore) ingest(ctx context.Context, report api.UsageReport, now time.Time) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	for p, rules := range report.Chambers {
+		records, err := us.get(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		for key, n := range rules {
+			r := records[key]
+			r.Count += n
+			r.LastEvaluatedAt = now
+			r.LastEvaluatedBy = report.Application
+			r.LastEvaluatedVersion = report.Version
+			records[key] = r
+		}
+
+		b, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		if err := us.strg.Put(ctx, storage.StorageEntry{Key: usagePath(p), Value: b}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleUsage ingests usage reports on POST /v1/usage and returns the usage records of a chamber's rules on GET /v1/usage/{path}.
+// Rules of the chamber that were never reported are included with a zero count,
+// and the unusedSince query parameter limits the records to rules that were not evaluated since the RFC 3339 timestamp.
+// Reading the records and reporting the usage of a chamber both require read on it
+func handleUsage(strg storage.Storage, us *usageStore, authz *authorizer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		ctx := r.Context()
+		logger := logging.Ctx(ctx)
+		errorLog := logger.ErrorCtx(ctx).Str("method", r.Method).Str("path", r.URL.Path)
+		span := trace.SpanFromContext(ctx)
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/usage":
+			var report api.UsageReport
+			if err := utils.ReadInterfaceWith(http.MaxBytesReader(w, r.Body, maxUsageReportSize), &report); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				errorLog.Msg(err.Error())
+				if errors.Is(err, io.EOF) {
+					err = errors.New("request body must not be empty")
+				} else {
+					err = errors.New(http.StatusText(http.StatusBadRequest))
+				}
+				handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{err.Error()}))
+				return
+			}
+
+			if report.Application == "" {
+				handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{"application must not be empty"}))
+				return
+			}
+			for p := range report.Chambers {
+				if err := storage.ValidatePath(p); err != nil {
+					handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{fmt.Sprintf("invalid path %q: %s", p, err.Error())}))
+					return
+				}
+				if p := usagePath(p); !authz.allowed(ctx, p, ReadCapability) {
+					msg := fmt.Sprintf("%s is not permitted on %s", ReadCapability, p)
+					span.SetStatus(codes.Error, msg)
+					handleUnauthorized(ctx, w, msg)
+					return
+				}
+			}
+
+			span.SetAttributes(attribute.String("realm.server.usage.application", report.Application))
+			if err := us.ingest(ctx, report, time.Now().UTC()); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				errorLog.Msg(err.Error())
+				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				return
+			}
+			handleOk(w, nil)
+
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/usage/"):
+			escaped := strings.TrimPrefix(r.URL.Path, "/v1/usage")
+			p, err := url.PathUnescape(escaped)
+			if err == nil {
+				err = storage.ValidatePath(p)
+			}
+			if err != nil {
+				handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{fmt.Sprintf("invalid path %q: %s", escaped, err.Error())}))
+				return
+			}
+			p = utils.EnsureTrailingSlash(p)
+			span.SetAttributes(attribute.String("realm.server.logicalPath", p))
+			if !authz.allowed(ctx, p, ReadCapability) {
+				msg := fmt.Sprintf("%s is not permitted on %s", ReadCapability, p)
+				span.SetStatus(codes.Error, msg)
+				handleUnauthorized(ctx, w, msg)
+				return
+			}
+
+			var unusedSince time.Time
+			if s := r.URL.Query().Get("unusedSince"); s != "" {
+				t, err := time.Parse(time.RFC3339, s)
+				if err != nil {
+					handleError(ctx, w, http.StatusBadRequest, createResponseWithErrors(nil, []string{fmt.Sprintf("invalid unusedSince %q: must be an RFC 3339 timestamp", s)}))
+					return
+				}
+				unusedSince = t
+			}
+
+			records, err := us.get(ctx, p)
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				errorLog.Msg(err.Error())
+				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				return
+			}
+
+			// include the rules that were never evaluated
+			if entry, err := strg.Get(ctx, p); err == nil {
+				var c realm.Chamber
+				if err := json.Unmarshal(entry.Value, &c); err == nil {
+					for key := range c.Rules {
+						if _, ok := records[key]; !ok {
+							records[key] = api.RuleUsage{}
+						}
+					}
+				}
+			}
+
+			if !unusedSince.IsZero() {
+				for key, record := range records {
+					if !record.LastEvaluatedAt.Before(unusedSince) {
+						delete(records, key)
+					}
+				}
+			}
+
+			raw, err := json.Marshal(records)
+			if err != nil {
+				handleError(ctx, w, http.StatusInternalServerError, createResponseWithErrors(nil, []string{err.Error()}))
+				return
+			}
+			handleOk(w, createResponseWithErrors(raw, nil))
+
+		default:
+			span.SetStatus(codes.Error, "method not allowed")
+			handleError(ctx, w, http.StatusMethodNotAllowed, createResponseWithErrors(nil, []string{http.StatusText(http.StatusMethodNotAllowed)}))
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	realmhttp "github.com/steviebps/realm/http"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestUsage(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chamber := `{"rules":{
+		"enabled":{"type":"boolean","value":true},
+		"message":{"type":"string","value":"hello"},
+		"unused":{"type":"number","value":1}
+	}}`
+	if err := stg.Put(context.Background(), storage.StorageEntry{Key: "/app/", Value: []byte(chamber)}); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := realmhttp.NewHandler(context.Background(), realmhttp.HandlerConfig{Storage: stg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method string, target string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	usage := func(target string) map[string]api.RuleUsage {
+		t.Helper()
+		rec := do(http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d but returned %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var res api.HTTPErrorAndDataResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		var records map[string]api.RuleUsage
+		if err := json.Unmarshal(res.Data, &records); err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	before := time.Now().UTC()
+	reports := []string{
+		`{"application":"checkout","version":"v1.0.0","chambers":{"app":{"enabled":2,"message":1}}}`,
+		`{"application":"billing","version":"v2.0.0","chambers":{"/app/":{"enabled":3}}}`,
+	}
+	for _, report := range reports {
+		if rec := do(http.MethodPost, "/v1/usage", report); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected status %d but returned %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+		}
+	}
+
+	records := usage("/v1/usage/app")
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records but returned %+v", records)
+	}
+	enabled, message, unused := records["enabled"], records["message"], records["unused"]
+	if enabled.Count != 5 || enabled.LastEvaluatedBy != "billing" || enabled.LastEvaluatedVersion != "v2.0.0" || enabled.LastEvaluatedAt.Before(before) {
+		t.Errorf("expected the counts of enabled to be aggregated but returned %+v", enabled)
+	}
+	if message.Count != 1 || message.LastEvaluatedBy != "checkout" || message.LastEvaluatedVersion != "v1.0.0" {
+		t.Errorf("unexpected record of message: %+v", message)
+	}
+	if unused.Count != 0 || !unused.LastEvaluatedAt.IsZero() || unused.LastEvaluatedBy != "" {
+		t.Errorf("expected a zero record for the rule that was never reported but returned %+v", unused)
+	}
+
+	tests := []struct {
+		name        string
+		unusedSince time.Time
+		expected    []string
+	}{
+		{"rules unused since before the reports", before.Add(-time.Hour), []string{"unused"}},
+		{"rules unused since after the reports", before.Add(time.Hour), []string{"enabled", "message", "unused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := usage("/v1/usage/app?unusedSince=" + tt.unusedSince.Format(time.RFC3339))
+			if len(records) != len(tt.expected) {
+				t.Fatalf("expected %v but returned %+v", tt.expected, records)
+			}
+			for _, key := range tt.expected {
+				if _, ok := records[key]; !ok {
+					t.Errorf("expected %q in %+v", key, records)
+				}
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"report without application", http.MethodPost, "/v1/usage", `{"chambers":{"app":{"enabled":1}}}`, http.StatusBadRequest},
+		{"report with invalid path", http.MethodPost, "/v1/usage", `{"application":"checkout","chambers":{"app/../other":{"enabled":1}}}`, http.StatusBadRequest},
+		{"empty report", http.MethodPost, "/v1/usage", ``, http.StatusBadRequest},
+		{"invalid unusedSince", http.MethodGet, "/v1/usage/app?unusedSince=yesterday", "", http.StatusBadRequest},
+		{"path referencing parents", http.MethodGet, "/v1/usage/app%252F%252E%252E", "", http.StatusBadRequest},
+		{"path with invalid escape", http.MethodGet, "/v1/usage/app%25zz", "", http.StatusBadRequest},
+		{"method not allowed", http.MethodDelete, "/v1/usage/app", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(tt.method, tt.target, tt.body); rec.Code != tt.status {
+				t.Errorf("expected status %d but returned %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package openfeature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	realm "github.com/steviebps/realm/pkg"
+)
+
+const (
+	// VersionContextKey is the evaluation context attribute used as the application version when evaluating overrides.
+	// The version realm was configured with is used when it is not set
+	VersionContextKey = "version"
+	// NamespaceContextKey is the evaluation context attribute used to evaluate flags from one of realm's namespaces
+	NamespaceContextKey = "namespace"
+)
+
+const eventBufferSize = 16
+
+// Provider is an OpenFeature provider backed by a Realm
+type Provider struct {
+	rlm         *realm.Realm
+	events      chan of.Event
+	mu          sync.Mutex
+	unsubscribe func()
+}
+
+var (
+	_ of.FeatureProvider          = (*Provider)(nil)
+	_ of.StateHandler             = (*Provider)(nil)
+	_ of.ContextAwareStateHandler = (*Provider)(nil)
+	_ of.EventHandler             = (*Provider)(nil)
+)
+
+// NewProvider returns a Provider that evaluates flags with rlm.
+// The provider owns the lifecycle of rlm: it is started when the provider is initialized and stopped on shutdown
+func NewProvider(rlm *realm.Realm) *Provider {
+	return &Provider{
+		rlm:    rlm,
+		events: make(chan of.Event, eventBufferSize),
+	}
+}
+
+func (p *Provider) Metadata() of.Metadata {
+	return of.Metadata{Name: "realm"}
+}
+
+func (p *Provider) Hooks() []of.Hook {
+	return []of.Hook{}
+}
+
+// Init starts realm and forwards its events to OpenFeature
+func (p *Provider) Init(evaluationContext of.EvaluationContext) error {
+	return p.InitWithContext(context.Background(), evaluationContext)
+}
+
+// InitWithContext starts realm, waiting for it to be ready until ctx is done, and forwards its events to OpenFeature
+func (p *Provider) InitWithContext(ctx context.Context, evaluationContext of.EvaluationContext) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.unsubscribe == nil {
+		p.unsubscribe = p.rlm.Subscribe(p.forward)
+	}
+	return p.rlm.Start(ctx)
+}
+
+// Shutdown stops realm
+func (p *Provider) Shutdown() {
+	p.ShutdownWithContext(context.Background())
+}
+
+// ShutdownWithContext stops realm, waiting for it to stop until ctx is done
+func (p *Provider) ShutdownWithContext(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.unsubscribe != nil {
+		p.unsubscribe()
+		p.unsubscribe = nil
+		return p.rlm.Stop(ctx)
+	}
+	return nil
+}
+
+func (p *Provider) EventChannel() <-chan of.Event {
+	return p.events
+}
+
+func (p *Provider) forward(e realm.Event) {
+	event := of.Event{ProviderName: p.Metadata().Name}
+	switch e.Type {
+	case realm.EventReady:
+		event.EventType = of.ProviderReady
+	case realm.EventStale:
+		event.EventType = of.ProviderStale
+		if e.Err != nil {
+			event.Message = e.Err.Error()
+		}
+	case realm.EventChanged:
+		event.EventType = of.ProviderConfigChange
+		event.FlagChanges = e.ChangedKeys
+		if e.Namespace != "" {
+			event.EventMetadata = map[string]any{NamespaceContextKey: e.Namespace}
+		}
+	default:
+		return
+	}
+
+	// never block realm's polling goroutine on a slow consumer
+	select {
+	case p.events <- event:
+	default:
+	}
+}
+
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, flatCtx of.FlattenedContext) of.BoolResolutionDetail {
+	eval, detail := p.evaluate(ctx, flag, flatCtx)
+	if detail.Error() != nil {
+		return of.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, ok := eval.Value.(bool)
+	if !ok {
+		return of.BoolResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(eval)}
+	}
+	return of.BoolResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, flatCtx of.FlattenedContext) of.StringResolutionDetail {
+	eval, detail := p.evaluate(ctx, flag, flatCtx)
+	if detail.Error() != nil {
+		return of.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, ok := eval.Value.(string)
+	if !ok {
+		return of.StringResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(eval)}
+	}
+	return of.StringResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, flatCtx of.FlattenedContext) of.FloatResolutionDetail {
+	eval, detail := p.evaluate(ctx, flag, flatCtx)
+	if detail.Error() != nil {
+		return of.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, ok := eval.Value.(float64)
+	if !ok {
+		return of.FloatResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(eval)}
+	}
+	return of.FloatResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// IntEvaluation evaluates number rules that hold a whole number
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, flatCtx of.FlattenedContext) of.IntResolutionDetail {
+	eval, detail := p.evaluate(ctx, flag, flatCtx)
+	if detail.Error() != nil {
+		return of.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	v, ok := eval.Value.(float64)
+	if !ok || v != math.Trunc(v) || v > math.MaxInt64 || v < math.MinInt64 {
+		return of.IntResolutionDetail{Value: defaultValue, ProviderResolutionDetail: typeMismatch(eval)}
+	}
+	return of.IntResolutionDetail{Value: int64(v), ProviderResolutionDetail: detail}
+}
+
+// ObjectEvaluation evaluates rules of any type. Custom rules are unmarshaled into generic JSON values
+func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue any, flatCtx of.FlattenedContext) of.InterfaceResolutionDetail {
+	eval, detail := p.evaluate(ctx, flag, flatCtx)
+	if detail.Error() != nil {
+		return of.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+
+	raw, ok := eval.Value.(*json.RawMessage)
+	if !ok {
+		return of.InterfaceResolutionDetail{Value: eval.Value, ProviderResolutionDetail: detail}
+	}
+
+	var v any
+	if err := json.Unmarshal(*raw, &v); err != nil {
+		detail.Reason = of.ErrorReason
+		detail.ResolutionError = of.NewParseErrorResolutionError(fmt.Sprintf("could not unmarshal custom rule %q", flag), err)
+		return of.InterfaceResolutionDetail{Value: defaultValue, ProviderResolutionDetail: detail}
+	}
+	return of.InterfaceResolutionDetail{Value: v, ProviderResolutionDetail: detail}
+}
+
+// evaluate resolves the flag from the chamber selected by the evaluation context.
+// The evaluation goes through realm so that it is observed like every other rule retrieval
+func (p *Provider) evaluate(ctx context.Context, flag string, flatCtx of.FlattenedContext) (realm.Evaluation, of.ProviderResolutionDetail) {
+	if v, ok := flatCtx[VersionContextKey].(string); ok && v != "" {
+		ec, _ := realm.EvaluationContextFrom(ctx)
+		ec.Version = v
+		ctx = realm.NewEvaluationContext(ctx, ec)
+	}
+
+	var eval realm.Evaluation
+	if ns, ok := flatCtx[NamespaceContextKey].(string); ok && ns != "" {
+		eval = p.rlm.In(ns).Evaluate(ctx, flag)
+	} else {
+		eval = p.rlm.Evaluate(ctx, flag)
+	}
+
+	if eval.Err != nil {
+		var nnf *realm.ErrNamespaceNotFound
+		var rnf *realm.ErrRuleNotFound
+		switch {
+		case errors.As(eval.Err, &nnf):
+			return eval, errorDetail(of.NewInvalidContextResolutionError(eval.Err.Error()))
+		case errors.Is(eval.Err, realm.ErrChamberEmpty):
+			return eval, errorDetail(of.NewProviderNotReadyResolutionError(eval.Err.Error()))
+		case errors.As(eval.Err, &rnf):
+			return eval, errorDetail(of.NewFlagNotFoundResolutionError(eval.Err.Error()))
+		}
+		return eval, errorDetail(of.NewGeneralResolutionError(eval.Err.Error()))
+	}
+
+	return eval, of.ProviderResolutionDetail{
+		Reason:  of.Reason(eval.Reason),
+		Variant: eval.Variant,
+	}
+}
+
+func errorDetail(err of.ResolutionError) of.ProviderResolutionDetail {
+	return of.ProviderResolutionDetail{Reason: of.ErrorReason, ResolutionError: err}
+}
+
+func typeMismatch(eval realm.Evaluation) of.ProviderResolutionDetail {
+	return errorDetail(of.NewTypeMismatchResolutionError(fmt.Sprintf("%q is of type %q", eval.Key, eval.Type)))
+}
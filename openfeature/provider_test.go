@@ -0,0 +1,133 @@
+package openfeature
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/steviebps/realm/client"
+	realm "github.com/steviebps/realm/pkg"
+)
+
+const testChamber = `{"data":{"rules":{
+	"enabled":{"type":"boolean","value":false,"overrides":[{"type":"boolean","value":true,"minimumVersion":"v2.0.0","maximumVersion":"v3.0.0"}]},
+	"message":{"type":"string","value":"hello"},
+	"limit":{"type":"number","value":10},
+	"ratio":{"type":"number","value":0.5},
+	"custom":{"type":"custom","value":{"foo":"bar"}}
+}}}`
+
+func newTestProvider(t *testing.T, opts ...realm.RealmOption) *Provider {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testChamber)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := client.NewHttpClient(&client.HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rlm, err := realm.NewRealm(append([]realm.RealmOption{realm.WithHttpClient(c), realm.WithPath("/"), realm.WithVersion("v1.0.0")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProvider(rlm)
+	if err := p.Init(of.EvaluationContext{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Shutdown)
+	return p
+}
+
+type evaluationResult struct {
+	value  any
+	detail of.ProviderResolutionDetail
+}
+
+func TestProviderEvaluations(t *testing.T) {
+	p := newTestProvider(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		eval    func() evaluationResult
+		expect  any
+		reason  of.Reason
+		variant string
+	}{
+		{"bool without matching override", func() evaluationResult {
+			d := p.BooleanEvaluation(ctx, "enabled", true, of.FlattenedContext{})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, false, of.DefaultReason, realm.VariantDefault},
+		{"bool with version from evaluation context", func() evaluationResult {
+			d := p.BooleanEvaluation(ctx, "enabled", false, of.FlattenedContext{VersionContextKey: "v2.1.0"})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, true, of.TargetingMatchReason, "override[v2.0.0,v3.0.0]"},
+		{"string", func() evaluationResult {
+			d := p.StringEvaluation(ctx, "message", "", of.FlattenedContext{})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, "hello", of.StaticReason, realm.VariantDefault},
+		{"int", func() evaluationResult {
+			d := p.IntEvaluation(ctx, "limit", 0, of.FlattenedContext{})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, int64(10), of.StaticReason, realm.VariantDefault},
+		{"int from fractional number", func() evaluationResult {
+			d := p.IntEvaluation(ctx, "ratio", 1, of.FlattenedContext{})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, int64(1), of.ErrorReason, ""},
+		{"missing flag", func() evaluationResult {
+			d := p.FloatEvaluation(ctx, "missing", 2.5, of.FlattenedContext{})
+			return evaluationResult{d.Value, d.ProviderResolutionDetail}
+		}, 2.5, of.ErrorReason, ""},
+	}
+
+	for _, test := range tests {
+		res := test.eval()
+		if res.value != test.expect {
+			t.Errorf("%s: expected value %v but returned %v", test.name, test.expect, res.value)
+		}
+		if res.detail.Reason != test.reason {
+			t.Errorf("%s: expected reason %q but returned %q", test.name, test.reason, res.detail.Reason)
+		}
+		if res.detail.Variant != test.variant {
+			t.Errorf("%s: expected variant %q but returned %q", test.name, test.variant, res.detail.Variant)
+		}
+	}
+
+	o := p.ObjectEvaluation(ctx, "custom", nil, of.FlattenedContext{})
+	m, ok := o.Value.(map[string]any)
+	if !ok || m["foo"] != "bar" {
+		t.Errorf("custom rule should be unmarshaled into a map but returned %v", o.Value)
+	}
+
+	b := p.BooleanEvaluation(ctx, "enabled", true, of.FlattenedContext{NamespaceContextKey: "unknown"})
+	if b.ResolutionDetail().ErrorCode != of.InvalidContextCode {
+		t.Errorf("unknown namespace should return %q but returned %q", of.InvalidContextCode, b.ResolutionDetail().ErrorCode)
+	}
+}
+
+func TestProviderEvaluationsAreObserved(t *testing.T) {
+	var observed []realm.Evaluation
+	p := newTestProvider(t, realm.WithEvaluationObserver(func(ctx context.Context, e realm.Evaluation) {
+		observed = append(observed, e)
+	}))
+	ctx := context.Background()
+
+	p.BooleanEvaluation(ctx, "enabled", false, of.FlattenedContext{VersionContextKey: "v2.1.0"})
+	p.StringEvaluation(ctx, "missing", "", of.FlattenedContext{})
+
+	if len(observed) != 2 {
+		t.Fatalf("expected 2 observed evaluations but returned %d: %+v", len(observed), observed)
+	}
+	if e := observed[0]; e.Key != "enabled" || e.Value != true || e.Reason != realm.ReasonTargetingMatch {
+		t.Errorf("expected the override of enabled to be observed but returned %+v", e)
+	}
+	if e := observed[1]; e.Key != "missing" || e.Err == nil {
+		t.Errorf("expected the missing flag to be observed with an error but returned %+v", e)
+	}
+}
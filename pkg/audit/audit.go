@@ -0,0 +1,174 @@
+// Package audit records the changes made to chambers through the realm server
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/utils"
+)
+
+const (
+	// DefaultQueryLimit is the number of events returned by a query without a limit
+	DefaultQueryLimit = 100
+	// MaxQueryLimit is the maximum number of events returned by a query
+	MaxQueryLimit = 1000
+)
+
+// Sink records audit events
+type Sink interface {
+	Write(ctx context.Context, event api.AuditEvent) error
+}
+
+// Querier is implemented by the sinks that audit events can be read back from
+type Querier interface {
+	Query(ctx context.Context, q Query) ([]api.AuditEvent, error)
+}
+
+// Query selects audit events by chamber path and time range
+type Query struct {
+	// Path limits the events to the changes of the chamber at Path, every chamber matches when it is empty
+	Path string
+	// Recursive also includes the changes of the chambers below Path
+	Recursive bool
+	// Since and Until bound the time of the events, they are not bounded when zero
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of events returned, most recent first. DefaultQueryLimit is used when it is not positive
+	Limit int
+	// Filter, when set, excludes the events it returns false for before the limit is applied
+	Filter func(api.AuditEvent) bool
+}
+
+// Matches reports whether event is selected by q, ignoring the limit
+func (q Query) Matches(event api.AuditEvent) bool {
+	if !q.Since.IsZero() && event.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && event.Time.After(q.Until) {
+		return false
+	}
+	if q.Path != "" {
+		p := normalizePath(q.Path)
+		if q.Recursive && !strings.HasPrefix(event.Path, p) {
+			return false
+		}
+		if !q.Recursive && event.Path != p {
+			return false
+		}
+	}
+	return q.Filter == nil || q.Filter(event)
+}
+
+func (q Query) limit() int {
+	switch {
+	case q.Limit <= 0:
+		return DefaultQueryLimit
+	case q.Limit > MaxQueryLimit:
+		return MaxQueryLimit
+	}
+	return q.Limit
+}
+
+// newest sorts events from the most recent and truncates them to the limit of q
+func (q Query) newest(events []api.AuditEvent) []api.AuditEvent {
+	slices.SortStableFunc(events, func(a, b api.AuditEvent) int {
+		return b.Time.Compare(a.Time)
+	})
+	if len(events) > q.limit() {
+		events = events[:q.limit()]
+	}
+	return events
+}
+
+func normalizePath(p string) string {
+	return utils.EnsureTrailingSlash("/" + strings.TrimPrefix(p, "/"))
+}
+
+// MultiSink writes audit events to every one of its sinks
+type MultiSink []Sink
+
+func (ms MultiSink) Write(ctx context.Context, event api.AuditEvent) error {
+	var errs []error
+	for _, s := range ms {
+		if err := s.Write(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Query queries the first of its sinks that can be queried
+func (ms MultiSink) Query(ctx context.Context, q Query) ([]api.AuditEvent, error) {
+	for _, s := range ms {
+		if querier, ok := s.(Querier); ok {
+			return querier.Query(ctx, q)
+		}
+	}
+	return nil, ErrNotQueryable
+}
+
+// ErrNotQueryable is returned when none of the sinks can be queried
+var ErrNotQueryable = errors.New("audit log cannot be queried")
+
+// Diff returns the changes of the rules between two versions of a chamber, ordered by rule key. A nil chamber has no rules
+func Diff(before *realm.Chamber, after *realm.Chamber) ([]api.RuleChange, error) {
+	rules := func(c *realm.Chamber) map[string]*realm.OverrideableRule {
+		if c == nil {
+			return nil
+		}
+		return c.Rules
+	}
+	beforeRules, afterRules := rules(before), rules(after)
+
+	keys := make(map[string]struct{}, len(beforeRules)+len(afterRules))
+	for k := range beforeRules {
+		keys[k] = struct{}{}
+	}
+	for k := range afterRules {
+		keys[k] = struct{}{}
+	}
+
+	changes := []api.RuleChange{}
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		b, err := marshalRule(beforeRules, k)
+		if err != nil {
+			return nil, err
+		}
+		a, err := marshalRule(afterRules, k)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case b == nil:
+			changes = append(changes, api.RuleChange{Key: k, Change: api.RuleAdded, After: a})
+		case a == nil:
+			changes = append(changes, api.RuleChange{Key: k, Change: api.RuleRemoved, Before: b})
+		case !bytes.Equal(a, b):
+			changes = append(changes, api.RuleChange{Key: k, Change: api.RuleModified, Before: b, After: a})
+		}
+	}
+	return changes, nil
+}
+
+func marshalRule(rules map[string]*realm.OverrideableRule, key string) (json.RawMessage, error) {
+	r, ok := rules[key]
+	if !ok {
+		return nil, nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal rule %q: %w", key, err)
+	}
+	return b, nil
+}
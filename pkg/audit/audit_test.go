@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+func TestDiff(t *testing.T) {
+	before := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"kept":    {Rule: &realm.Rule{Type: "boolean", Value: true}},
+		"changed": {Rule: &realm.Rule{Type: "string", Value: "a"}},
+		"removed": {Rule: &realm.Rule{Type: "number", Value: 1.0}},
+	}}
+	after := &realm.Chamber{Rules: map[string]*realm.OverrideableRule{
+		"kept":    {Rule: &realm.Rule{Type: "boolean", Value: true}},
+		"changed": {Rule: &realm.Rule{Type: "string", Value: "b"}},
+		"added":   {Rule: &realm.Rule{Type: "boolean", Value: false}},
+	}}
+
+	tests := []struct {
+		name     string
+		before   *realm.Chamber
+		after    *realm.Chamber
+		expected []api.RuleChange
+	}{
+		{"changed chamber", before, after, []api.RuleChange{
+			{Key: "added", Change: api.RuleAdded, After: json.RawMessage(`{"type":"boolean","value":false}`)},
+			{Key: "changed", Change: api.RuleModified, Before: json.RawMessage(`{"type":"string","value":"a"}`), After: json.RawMessage(`{"type":"string","value":"b"}`)},
+			{Key: "removed", Change: api.RuleRemoved, Before: json.RawMessage(`{"type":"number","value":1}`)},
+		}},
+		{"created chamber", nil, &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"added": after.Rules["added"]}}, []api.RuleChange{
+			{Key: "added", Change: api.RuleAdded, After: json.RawMessage(`{"type":"boolean","value":false}`)},
+		}},
+		{"deleted chamber", &realm.Chamber{Rules: map[string]*realm.OverrideableRule{"kept": before.Rules["kept"]}}, nil, []api.RuleChange{
+			{Key: "kept", Change: api.RuleRemoved, Before: json.RawMessage(`{"type":"boolean","value":true}`)},
+		}},
+		{"unchanged chamber", before, before, []api.RuleChange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			equal := slices.EqualFunc(changes, tt.expected, func(a, b api.RuleChange) bool {
+				return a.Key == b.Key && a.Change == b.Change && bytes.Equal(a.Before, b.Before) && bytes.Equal(a.After, b.After)
+			})
+			if !equal {
+				t.Errorf("expected %+v but returned %+v", tt.expected, changes)
+			}
+		})
+	}
+}
+
+func TestSinks(t *testing.T) {
+	fileSink, err := NewFileSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileSink.Close()
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileStg, err := storage.NewFileStorage(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	boltStg, err := storage.NewBoltStorage(map[string]string{"path": t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer boltStg.Close(context.Background())
+
+	start := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+	events := []api.AuditEvent{
+		{ID: "1", Time: start, Path: "/payments/", Operation: "put"},
+		{ID: "2", Time: start.Add(time.Hour), Path: "/payments/eu/", Operation: "patch"},
+		{ID: "3", Time: start.Add(2 * time.Hour), Path: "/billing/", Operation: "delete"},
+		{ID: "4", Time: start.Add(25 * time.Hour), Path: "/payments/", Operation: "patch"},
+	}
+
+	sinks := map[string]interface {
+		Sink
+		Querier
+	}{
+		"file":         fileSink,
+		"storage":      NewStorageSink(stg),
+		"file storage": NewStorageSink(fileStg),
+		"bolt storage": NewStorageSink(boltStg),
+	}
+	for name, sink := range sinks {
+		t.Run(name, func(t *testing.T) {
+			for _, e := range events {
+				if err := sink.Write(context.Background(), e); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			tests := []struct {
+				name     string
+				query    Query
+				expected []string
+			}{
+				{"everything, most recent first", Query{}, []string{"4", "3", "2", "1"}},
+				{"path", Query{Path: "payments"}, []string{"4", "1"}},
+				{"recursive path", Query{Path: "/payments/", Recursive: true}, []string{"4", "2", "1"}},
+				{"time range", Query{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, []string{"3", "2"}},
+				{"time range on another day", Query{Since: start.Add(24 * time.Hour)}, []string{"4"}},
+				{"limit", Query{Limit: 1}, []string{"4"}},
+				{"filter before limit", Query{Limit: 2, Filter: func(e api.AuditEvent) bool { return e.Path != "/payments/" }}, []string{"3", "2"}},
+				{"no match", Query{Path: "/missing/"}, []string{}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					found, err := sink.Query(context.Background(), tt.query)
+					if err != nil {
+						t.Fatal(err)
+					}
+					ids := []string{}
+					for _, e := range found {
+						ids = append(ids, e.ID)
+					}
+					if !slices.Equal(ids, tt.expected) {
+						t.Errorf("expected %v but returned %v", tt.expected, ids)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestMultiSink(t *testing.T) {
+	var buf bytes.Buffer
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms := MultiSink{NewWriterSink(&buf), NewStorageSink(stg)}
+	if err := ms.Write(context.Background(), api.AuditEvent{ID: "1", Time: time.Now(), Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var written api.AuditEvent
+	if err := json.Unmarshal(buf.Bytes(), &written); err != nil || written.ID != "1" {
+		t.Errorf("expected the event to be written as a JSON line but returned %q", buf.String())
+	}
+	found, err := ms.Query(context.Background(), Query{})
+	if err != nil || len(found) != 1 {
+		t.Errorf("expected the event to be queried from storage but returned %v, %v", found, err)
+	}
+
+	if _, err := (MultiSink{NewWriterSink(&buf)}).Query(context.Background(), Query{}); err != ErrNotQueryable {
+		t.Errorf("expected %v but returned %v", ErrNotQueryable, err)
+	}
+}
+
+// countingStorage counts the entries read from its storage
+type countingStorage struct {
+	storage.Storage
+	gets int
+}
+
+func (cs *countingStorage) Get(ctx context.Context, key string) (*storage.StorageEntry, error) {
+	cs.gets++
+	return cs.Storage.Get(ctx, key)
+}
+
+func TestStorageSinkReadsRecentEvents(t *testing.T) {
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingStorage{Storage: stg}
+	sink := NewStorageSink(counting)
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		e := api.AuditEvent{ID: strconv.Itoa(i), Time: start.Add(time.Duration(i) * 12 * time.Hour), Path: "/payments/"}
+		if err := sink.Write(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+		gets     int
+	}{
+		{"limit", Query{Limit: 3}, []string{"9", "8", "7"}, 3},
+		{"until", Query{Until: start.Add(24 * time.Hour), Limit: 2}, []string{"2", "1"}, 2},
+		{"since", Query{Since: start.Add(96 * time.Hour)}, []string{"9", "8"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counting.gets = 0
+			found, err := sink.Query(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, e := range found {
+				ids = append(ids, e.ID)
+			}
+			if !slices.Equal(ids, tt.expected) {
+				t.Errorf("expected %v but returned %v", tt.expected, ids)
+			}
+			if counting.gets != tt.gets {
+				t.Errorf("expected %d events to be read but read %d", tt.gets, counting.gets)
+			}
+		})
+	}
+}
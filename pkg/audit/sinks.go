@@ -0,0 +1,214 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steviebps/realm/api"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+// maxLineSize limits the size of an event read back from a JSON Lines file
+const maxLineSize = 4 << 20
+
+// WriterSink writes audit events to an io.Writer in JSON Lines format, e.g. to stdout
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (ws *WriterSink) Write(ctx context.Context, event api.AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	_, err = ws.w.Write(append(b, '\n'))
+	return err
+}
+
+// FileSink appends audit events to a file in JSON Lines format and queries them by reading the file
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("path of the audit log file must not be empty")
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log file: %w", err)
+	}
+	return &FileSink{path: path, f: f}, nil
+}
+
+func (fs *FileSink) Write(ctx context.Context, event api.AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, err := fs.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return fs.f.Sync()
+}
+
+func (fs *FileSink) Query(ctx context.Context, q Query) ([]api.AuditEvent, error) {
+	// writes are blocked while reading so that a partially written line is never read
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	f, err := os.Open(fs.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events := []api.AuditEvent{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLineSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var event api.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("could not unmarshal audit event: %w", err)
+		}
+		if q.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return q.newest(events), nil
+}
+
+func (fs *FileSink) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.f.Close()
+}
+
+// StorageSink stores audit events in a storage backend, grouped by the day they happened on
+type StorageSink struct {
+	strg storage.Storage
+}
+
+func NewStorageSink(strg storage.Storage) *StorageSink {
+	return &StorageSink{strg: strg}
+}
+
+const (
+	dayLayout   = "2006-01-02"
+	eventLayout = "20060102T150405.000000000"
+)
+
+// eventKey returns the key of event, which sorts by time within the directory of its day. It is a directory like the key of a chamber so that every storage backend lists it
+func eventKey(event api.AuditEvent) string {
+	t := event.Time.UTC()
+	return "/" + t.Format(dayLayout) + "/" + t.Format(eventLayout) + "-" + event.ID + "/"
+}
+
+func (ss *StorageSink) Write(ctx context.Context, event api.AuditEvent) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return ss.strg.Put(ctx, storage.StorageEntry{Key: eventKey(event), Value: b})
+}
+
+// Query walks the days from the most recent and stops once the limit of q is reached, so that only the recent part of the log is read
+func (ss *StorageSink) Query(ctx context.Context, q Query) ([]api.AuditEvent, error) {
+	days, err := ss.list(ctx, "/")
+	if err != nil {
+		var nfError *storage.NotFoundError
+		if errors.As(err, &nfError) || errors.Is(err, os.ErrNotExist) {
+			return []api.AuditEvent{}, nil
+		}
+		return nil, err
+	}
+
+	events := []api.AuditEvent{}
+	for _, day := range days {
+		d, err := time.Parse(dayLayout, day)
+		if err != nil {
+			continue
+		}
+		if !q.Until.IsZero() && d.After(q.Until) {
+			continue
+		}
+		if !q.Since.IsZero() && d.Add(24*time.Hour).Before(q.Since) {
+			// every remaining day is older
+			break
+		}
+
+		names, err := ss.list(ctx, "/"+day+"/")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			t, err := time.Parse(eventLayout, strings.SplitN(name, "-", 2)[0])
+			if err != nil || (!q.Until.IsZero() && t.After(q.Until)) {
+				continue
+			}
+			if !q.Since.IsZero() && t.Before(q.Since) {
+				break
+			}
+
+			entry, err := ss.strg.Get(ctx, "/"+day+"/"+name+"/")
+			if err != nil {
+				return nil, err
+			}
+			var event api.AuditEvent
+			if err := json.Unmarshal(entry.Value, &event); err != nil {
+				return nil, fmt.Errorf("could not unmarshal audit event %q: %w", name, err)
+			}
+			if !q.Matches(event) {
+				continue
+			}
+			events = append(events, event)
+			if len(events) == q.limit() {
+				return events, nil
+			}
+		}
+	}
+	return events, nil
+}
+
+// list returns the names below prefix from the most recent, as not every storage backend sorts them
+func (ss *StorageSink) list(ctx context.Context, prefix string) ([]string, error) {
+	names, err := ss.strg.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = strings.Trim(name, "/")
+	}
+	slices.Sort(names)
+	slices.Reverse(names)
+	return names, nil
+}
@@ -0,0 +1,313 @@
+package realm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bindTag is the struct tag used to bind a field to a rule.
+// The tag value is the rule key optionally followed by a default value, e.g. `realm:"db.pool.size,default=10"`
+const bindTag = "realm"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ErrBind is returned when a struct could not be fully bound to a chamber.
+// Fields of rules that are missing or could not be converted keep their zero value
+type ErrBind struct {
+	// Missing is the keys of rules that do not exist and have no default value
+	Missing []string
+	// Errs holds the errors of rules that could not be converted to their field's type
+	Errs []error
+}
+
+func (be *ErrBind) Error() string {
+	var msgs []string
+	if len(be.Missing) > 0 {
+		msgs = append(msgs, fmt.Sprintf("missing rules: %s", strings.Join(be.Missing, ", ")))
+	}
+	for _, err := range be.Errs {
+		msgs = append(msgs, err.Error())
+	}
+	return "could not bind struct: " + strings.Join(msgs, "; ")
+}
+
+func (be *ErrBind) Unwrap() []error {
+	return be.Errs
+}
+
+// Bind sets the fields of the struct pointed to by v from the rules of the chamber associated with ctx.
+// Fields are bound by their realm tag, e.g. `realm:"db.pool.size,default=10"`, and fields without one are ignored.
+// Number rules can be bound to any integer or float field, string rules to time.Duration fields
+// and custom rules to any type that they can be unmarshaled into
+func (rlm *Realm) Bind(ctx context.Context, v any) error {
+	return rlm.bind(ctx, rlm.chamberFromContext(ctx), "", v, true)
+}
+
+// Bind sets the fields of the struct pointed to by v from the rules of the namespace. See Realm.Bind
+func (ns *Namespace) Bind(ctx context.Context, v any) error {
+	c, err := ns.Chamber(ctx)
+	if err != nil {
+		return err
+	}
+	return ns.rlm.bind(ctx, c, ns.name, v, true)
+}
+
+// bind sets the fields of v from c. The evaluations are only observed when observe is true,
+// rebinds made on refreshes are not retrievals made by the application
+func (rlm *Realm) bind(ctx context.Context, c *ChamberEntry, namespace string, v any, observe bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("can only bind to a non-nil pointer to a struct, got %T", v)
+	}
+	if c == nil {
+		return ErrChamberEmpty
+	}
+
+	be := &ErrBind{}
+	rlm.bindStruct(ctx, c, namespace, rv.Elem(), be, observe)
+	if len(be.Missing) > 0 || len(be.Errs) > 0 {
+		return be
+	}
+	return nil
+}
+
+func (rlm *Realm) bindStruct(ctx context.Context, c *ChamberEntry, namespace string, sv reflect.Value, be *ErrBind, observe bool) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		field := st.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(bindTag)
+		if !ok {
+			// untagged nested structs are bound with their own tags
+			if field.Type.Kind() == reflect.Struct && field.Type != durationType {
+				rlm.bindStruct(ctx, c, namespace, sv.Field(i), be, observe)
+			}
+			continue
+		}
+
+		key, opts, _ := strings.Cut(tag, ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		defaultValue, hasDefault := strings.CutPrefix(opts, "default=")
+
+		fv := sv.Field(i)
+		e := evaluate(ctx, c, namespace, key)
+		if e.Err != nil {
+			var rnf *ErrRuleNotFound
+			switch {
+			case errors.As(e.Err, &rnf) && hasDefault:
+				e.Reason = ReasonDefault
+				e.Err = nil
+				if err := setFromString(fv, defaultValue); err != nil {
+					be.Errs = append(be.Errs, fmt.Errorf("invalid default value for %q: %w", key, err))
+				}
+			case errors.As(e.Err, &rnf):
+				be.Missing = append(be.Missing, key)
+			default:
+				be.Errs = append(be.Errs, e.Err)
+			}
+			if observe {
+				rlm.observe(ctx, e)
+			}
+			continue
+		}
+
+		if err := setFromRule(fv, e.Value); err != nil {
+			e.fail(&ErrCouldNotConvertRule{Key: key, Type: e.Type})
+			be.Errs = append(be.Errs, fmt.Errorf("%w into field %s of type %s", e.Err, field.Name, field.Type))
+		}
+		if observe {
+			rlm.observe(ctx, e)
+		}
+	}
+}
+
+// setFromRule sets fv to a rule value which is a bool, string, float64 or *json.RawMessage
+func setFromRule(fv reflect.Value, value any) error {
+	if raw, ok := value.(*json.RawMessage); ok {
+		return json.Unmarshal(*raw, fv.Addr().Interface())
+	}
+
+	if fv.Type() == durationType {
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("durations must be strings")
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("value is not a boolean")
+		}
+		fv.SetBool(b)
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("value is not a string")
+		}
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 || fv.OverflowInt(int64(f)) {
+			return fmt.Errorf("value is not a whole number that fits in the field")
+		}
+		fv.SetInt(int64(f))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := value.(float64)
+		if !ok || f != math.Trunc(f) || f < 0 || f > math.MaxUint64 || fv.OverflowUint(uint64(f)) {
+			return fmt.Errorf("value is not a positive whole number that fits in the field")
+		}
+		fv.SetUint(uint64(f))
+	case reflect.Float32, reflect.Float64:
+		f, ok := value.(float64)
+		if !ok || fv.OverflowFloat(f) {
+			return fmt.Errorf("value is not a number that fits in the field")
+		}
+		fv.SetFloat(f)
+	case reflect.Interface:
+		rv := reflect.ValueOf(value)
+		if !rv.IsValid() {
+			return fmt.Errorf("value is nil")
+		}
+		if !rv.Type().AssignableTo(fv.Type()) {
+			return fmt.Errorf("value is not assignable to the field")
+		}
+		fv.Set(rv)
+	default:
+		return fmt.Errorf("unsupported field type")
+	}
+	return nil
+}
+
+// setFromString sets fv to the default value s of a realm tag
+func setFromString(fv reflect.Value, s string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return json.Unmarshal([]byte(s), fv.Addr().Interface())
+	}
+	return nil
+}
+
+// Binding holds a struct of type T bound to a Realm's primary chamber that is kept up to date across refreshes.
+// Every refresh that changes the chamber binds a new copy of T, so values returned by Load are never modified
+type Binding[T any] struct {
+	rlm         *Realm
+	current     atomic.Pointer[T]
+	mu          sync.Mutex
+	unsubscribe func()
+	lastErr     error
+}
+
+// NewBinding binds a new T to the current chamber of rlm and rebinds it every time the chamber changes.
+// It returns an error if the initial bind fails, while failed rebinds keep the previously bound value.
+// Only the initial bind is observed, rebinds are not counted as evaluations.
+// Close should be called once the binding is no longer needed
+func NewBinding[T any](ctx context.Context, rlm *Realm) (*Binding[T], error) {
+	b := &Binding[T]{rlm: rlm}
+	if err := b.rebind(ctx, true); err != nil {
+		return nil, err
+	}
+
+	b.unsubscribe = rlm.Subscribe(func(e Event) {
+		if e.Type != EventChanged || e.Namespace != "" {
+			return
+		}
+		ctx := rlm.logger.WithContext(context.Background())
+		if err := b.rebind(ctx, false); err != nil {
+			rlm.logger.ErrorCtx(ctx).Str("error", err.Error()).Msgf("could not rebind %T, keeping previous value", *new(T))
+		}
+	})
+	return b, nil
+}
+
+// Load returns the most recently bound value. It must not be modified
+func (b *Binding[T]) Load() *T {
+	return b.current.Load()
+}
+
+// Err returns the error of the most recent rebind or nil if it succeeded
+func (b *Binding[T]) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastErr
+}
+
+// Close stops rebinding on refreshes
+func (b *Binding[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.unsubscribe != nil {
+		b.unsubscribe()
+		b.unsubscribe = nil
+	}
+}
+
+func (b *Binding[T]) rebind(ctx context.Context, observe bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	v := new(T)
+	err := b.rlm.bind(ctx, b.rlm.getChamber(), "", v, observe)
+	b.lastErr = err
+	if err != nil {
+		return err
+	}
+	b.current.Store(v)
+	return nil
+}
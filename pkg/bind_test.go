@@ -0,0 +1,162 @@
+package realm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type staticGetter struct {
+	mu      sync.Mutex
+	chamber string
+}
+
+func (g *staticGetter) GetChamber(ctx context.Context, path string) (*Chamber, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var c Chamber
+	if err := json.Unmarshal([]byte(g.chamber), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (g *staticGetter) set(chamber string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.chamber = chamber
+}
+
+type testConfig struct {
+	Enabled  bool          `realm:"enabled"`
+	PoolSize int           `realm:"db.pool.size,default=10"`
+	Ratio    float32       `realm:"ratio"`
+	Timeout  time.Duration `realm:"timeout,default=5s"`
+	Hosts    []string      `realm:"hosts"`
+	Nested   struct {
+		Name string `realm:"name"`
+	}
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	g := &staticGetter{chamber: `{"rules":{
+		"enabled":{"type":"boolean","value":true},
+		"ratio":{"type":"number","value":0.25},
+		"hosts":{"type":"custom","value":["a","b"]},
+		"name":{"type":"string","value":"realm"}
+	}}`}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	var cfg testConfig
+	if err := rlm.Bind(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Enabled || cfg.PoolSize != 10 || cfg.Ratio != 0.25 || cfg.Timeout != 5*time.Second || !slices.Equal(cfg.Hosts, []string{"a", "b"}) || cfg.Nested.Name != "realm" {
+		t.Errorf("unexpected bound struct: %+v", cfg)
+	}
+
+	var missing struct {
+		Enabled bool   `realm:"enabled"`
+		Missing string `realm:"missing"`
+		Wrong   int    `realm:"ratio"`
+	}
+	err = rlm.Bind(context.Background(), &missing)
+	var be *ErrBind
+	if !errors.As(err, &be) {
+		t.Fatalf("expected ErrBind but returned: %v", err)
+	}
+	if !slices.Equal(be.Missing, []string{"missing"}) || len(be.Errs) != 1 || !missing.Enabled {
+		t.Errorf("unexpected bind error: %v", err)
+	}
+}
+
+func TestBindingRebindsOnChange(t *testing.T) {
+	g := &staticGetter{chamber: `{"rules":{"enabled":{"type":"boolean","value":false}}}`}
+	var observed int
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/"), WithEvaluationObserver(func(ctx context.Context, e Evaluation) {
+		observed++
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	type flags struct {
+		Enabled bool `realm:"enabled"`
+	}
+	b, err := NewBinding[flags](context.Background(), rlm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	first := b.Load()
+	if first.Enabled {
+		t.Fatalf("enabled should be false")
+	}
+
+	g.set(`{"rules":{"enabled":{"type":"boolean","value":true}}}`)
+	if err := rlm.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !b.Load().Enabled {
+		t.Errorf("binding should be updated after refresh")
+	}
+	if first.Enabled {
+		t.Errorf("previously loaded value should not be modified")
+	}
+	// rebinds are not evaluations made by the application
+	if observed != 1 {
+		t.Errorf("expected only the initial bind to be observed but observed %d evaluations", observed)
+	}
+}
+
+func TestSetFromRuleInterface(t *testing.T) {
+	var v struct {
+		Any      any
+		Stringer fmt.Stringer
+	}
+	sv := reflect.ValueOf(&v).Elem()
+
+	tests := []struct {
+		name    string
+		field   int
+		value   any
+		wantErr bool
+	}{
+		{"assignable value", 0, "realm", false},
+		{"nil value", 0, nil, true},
+		{"unset interface field", 1, "realm", true},
+		{"nil value into unset interface field", 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setFromRule(sv.Field(tt.field), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v but returned %v", tt.wantErr, err)
+			}
+		})
+	}
+	if v.Any != "realm" {
+		t.Errorf("expected the assignable value to be set but returned %v", v.Any)
+	}
+}
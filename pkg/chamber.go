@@ -1,7 +1,9 @@
 package realm
 
 import (
+	"bytes"
 	"encoding/json"
+	"slices"
 )
 
 // Chamber is a struct that holds metadata and rules
@@ -61,6 +63,54 @@ func NewChamberEntry(c *Chamber, version string) *ChamberEntry {
 	}
 }
 
+// Version returns the application version used when evaluating rules of the chamber entry
+func (c *ChamberEntry) Version() string {
+	return c.version
+}
+
+// Keys returns the sorted keys of every rule in the chamber entry
+func (c *ChamberEntry) Keys() []string {
+	keys := make([]string, 0, len(c.rules))
+	for k := range c.rules {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// changedKeys returns the sorted keys of rules that were added, removed or modified since prev
+func (c *ChamberEntry) changedKeys(prev *ChamberEntry) []string {
+	var changed []string
+	for k, rule := range c.rules {
+		prevRule, ok := prev.rules[k]
+		if !ok || !rulesEqual(rule, prevRule) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range prev.rules {
+		if _, ok := c.rules[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func rulesEqual(a, b *OverrideableRule) bool {
+	if a == b {
+		return true
+	}
+	aj, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aj, bj)
+}
+
 // Get returns the rule with the specified ruleKey.
 // Will return nil if the rule does not exist
 func (c *ChamberEntry) Get(ruleKey string) *OverrideableRule {
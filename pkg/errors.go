@@ -7,6 +7,8 @@ import (
 
 var (
 	ErrChamberEmpty = errors.New("chamber is nil")
+	// ErrRealmStopped is returned when starting a realm that was stopped
+	ErrRealmStopped = errors.New("realm has been stopped")
 )
 
 type ErrRuleNotFound struct {
@@ -25,3 +27,11 @@ type ErrCouldNotConvertRule struct {
 func (cnc *ErrCouldNotConvertRule) Error() string {
 	return fmt.Sprintf("%q could not be converted: it is of type %q", cnc.Key, cnc.Type)
 }
+
+type ErrNamespaceNotFound struct {
+	Name string
+}
+
+func (nnf *ErrNamespaceNotFound) Error() string {
+	return fmt.Sprintf("namespace %q is not subscribed to", nnf.Name)
+}
@@ -0,0 +1,109 @@
+package realm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Reason describes why a rule evaluated to its value
+type Reason string
+
+const (
+	// ReasonStatic is used when the rule has no overrides and its value was returned
+	ReasonStatic Reason = "STATIC"
+	// ReasonDefault is used when none of the rule's overrides apply to the version and its value was returned
+	ReasonDefault Reason = "DEFAULT"
+	// ReasonTargetingMatch is used when an override applies to the version and its value was returned
+	ReasonTargetingMatch Reason = "TARGETING_MATCH"
+	// ReasonError is used when the rule could not be evaluated
+	ReasonError Reason = "ERROR"
+)
+
+// VariantDefault is the variant of an evaluation that returned the rule's own value
+const VariantDefault = "default"
+
+// Evaluation holds the resolved value of a rule along with why it was resolved
+type Evaluation struct {
+	Key string
+	// Namespace is the namespace the rule was retrieved from. It is empty for the primary chamber
+	Namespace string
+	Value     interface{}
+	Type      string
+	Reason    Reason
+	Variant   string
+	Err       error
+}
+
+// Evaluate evaluates the rule at the version of the chamber entry
+func (c *ChamberEntry) Evaluate(ruleKey string) Evaluation {
+	return c.EvaluateAtVersion(ruleKey, c.version)
+}
+
+// EvaluateAtVersion evaluates the rule at the specified version.
+// The evaluation's Err is set to ErrRuleNotFound if the rule does not exist
+func (c *ChamberEntry) EvaluateAtVersion(ruleKey string, version string) Evaluation {
+	t := c.Get(ruleKey)
+	if t == nil {
+		return Evaluation{Key: ruleKey, Reason: ReasonError, Err: &ErrRuleNotFound{Key: ruleKey}}
+	}
+
+	if override := t.OverrideAtVersion(version); override != nil {
+		return Evaluation{Key: ruleKey, Value: override.Value, Type: t.Type, Reason: ReasonTargetingMatch, Variant: overrideVariant(override)}
+	}
+
+	reason := ReasonStatic
+	if len(t.Overrides) > 0 {
+		reason = ReasonDefault
+	}
+	return Evaluation{Key: ruleKey, Value: t.Value, Type: t.Type, Reason: reason, Variant: VariantDefault}
+}
+
+func overrideVariant(o *Override) string {
+	return fmt.Sprintf("override[%s,%s]", o.MinimumVersion, o.MaximumVersion)
+}
+
+func (e *Evaluation) fail(err error) {
+	e.Reason = ReasonError
+	e.Err = err
+}
+
+func (e *Evaluation) boolValue(defaultValue bool) (bool, error) {
+	if e.Err == nil {
+		if v, ok := e.Value.(bool); ok {
+			return v, nil
+		}
+		e.fail(&ErrCouldNotConvertRule{Key: e.Key, Type: e.Type})
+	}
+	return defaultValue, e.Err
+}
+
+func (e *Evaluation) stringValue(defaultValue string) (string, error) {
+	if e.Err == nil {
+		if v, ok := e.Value.(string); ok {
+			return v, nil
+		}
+		e.fail(&ErrCouldNotConvertRule{Key: e.Key, Type: e.Type})
+	}
+	return defaultValue, e.Err
+}
+
+func (e *Evaluation) float64Value(defaultValue float64) (float64, error) {
+	if e.Err == nil {
+		if v, ok := e.Value.(float64); ok {
+			return v, nil
+		}
+		e.fail(&ErrCouldNotConvertRule{Key: e.Key, Type: e.Type})
+	}
+	return defaultValue, e.Err
+}
+
+func (e *Evaluation) customValue(v any) error {
+	if e.Err != nil {
+		return e.Err
+	}
+	raw, ok := e.Value.(*json.RawMessage)
+	if !ok || json.Unmarshal(*raw, v) != nil {
+		e.fail(&ErrCouldNotConvertRule{Key: e.Key, Type: e.Type})
+	}
+	return e.Err
+}
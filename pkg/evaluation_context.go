@@ -0,0 +1,33 @@
+package realm
+
+import "context"
+
+// EvaluationContext holds the attributes of the caller a rule is evaluated for, e.g. extracted from an incoming request
+type EvaluationContext struct {
+	// Version is the application version overrides are evaluated at instead of the version set with WithVersion
+	Version string
+	// Attributes are additional attributes of the caller that are available to evaluation observers
+	Attributes map[string]string
+}
+
+var evaluationContextKey = &contextKey{"realm-evaluation"}
+
+// NewEvaluationContext returns a copy of ctx associated with ec.
+// Rules retrieved with the returned context are evaluated at ec.Version when it is not empty
+func NewEvaluationContext(ctx context.Context, ec EvaluationContext) context.Context {
+	return context.WithValue(ctx, evaluationContextKey, ec)
+}
+
+// EvaluationContextFrom returns the evaluation context associated with ctx, if any
+func EvaluationContextFrom(ctx context.Context) (EvaluationContext, bool) {
+	ec, ok := ctx.Value(evaluationContextKey).(EvaluationContext)
+	return ec, ok
+}
+
+// versionFromContext returns the version of the evaluation context associated with ctx or the version of the chamber entry
+func versionFromContext(ctx context.Context, c *ChamberEntry) string {
+	if ec, ok := EvaluationContextFrom(ctx); ok && ec.Version != "" {
+		return ec.Version
+	}
+	return c.version
+}
@@ -0,0 +1,92 @@
+package realm
+
+// EventType is the type of an Event emitted by Realm
+type EventType string
+
+const (
+	// EventReady is emitted when realm is able to serve rules again after being stale
+	EventReady EventType = "ready"
+	// EventStale is emitted when realm could not refresh one of its chambers and is serving previously retrieved rules
+	EventStale EventType = "stale"
+	// EventChanged is emitted when a refresh retrieved rules that differ from the ones being served
+	EventChanged EventType = "changed"
+)
+
+// Event is emitted by Realm after a refresh of its chambers
+type Event struct {
+	Type EventType
+	// Namespace is the namespace the event applies to. It is empty for the primary chamber
+	Namespace string
+	// ChangedKeys is the sorted keys of rules that were added, removed or modified. Only set for EventChanged
+	ChangedKeys []string
+	// Err is the error that caused realm to become stale. Only set for EventStale
+	Err error
+}
+
+// Subscribe registers fn to be called with every event realm emits.
+// fn is called from realm's polling goroutine and should not block.
+// The returned function unregisters fn
+func (rlm *Realm) Subscribe(fn func(Event)) func() {
+	rlm.subMu.Lock()
+	defer rlm.subMu.Unlock()
+
+	id := rlm.nextSubID
+	rlm.nextSubID++
+	if rlm.subscribers == nil {
+		rlm.subscribers = make(map[int]func(Event))
+	}
+	rlm.subscribers[id] = fn
+
+	return func() {
+		rlm.subMu.Lock()
+		defer rlm.subMu.Unlock()
+		delete(rlm.subscribers, id)
+	}
+}
+
+func (rlm *Realm) emit(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	rlm.subMu.Lock()
+	subscribers := make([]func(Event), 0, len(rlm.subscribers))
+	for _, fn := range rlm.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	rlm.subMu.Unlock()
+
+	for _, e := range events {
+		for _, fn := range subscribers {
+			fn(e)
+		}
+	}
+}
+
+// changeEvents returns the events describing the differences between prev and next
+func changeEvents(prev *snapshot, next *snapshot) []Event {
+	var events []Event
+	if prev == nil {
+		return events
+	}
+
+	if keys := entryChanges(prev.root, next.root); len(keys) > 0 {
+		events = append(events, Event{Type: EventChanged, ChangedKeys: keys})
+	}
+	for name, entry := range next.namespaces {
+		if keys := entryChanges(prev.namespaces[name], entry); len(keys) > 0 {
+			events = append(events, Event{Type: EventChanged, Namespace: name, ChangedKeys: keys})
+		}
+	}
+	return events
+}
+
+func entryChanges(prev *ChamberEntry, next *ChamberEntry) []string {
+	if next == nil || prev == next {
+		return nil
+	}
+	if prev == nil {
+		return next.Keys()
+	}
+	return next.changedKeys(prev)
+}
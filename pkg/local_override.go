@@ -0,0 +1,264 @@
+package realm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steviebps/realm/utils"
+)
+
+const (
+	// DefaultLocalOverrideEnvPrefix is the prefix of environment variables used with WithLocalOverrideEnv
+	DefaultLocalOverrideEnvPrefix = "REALM_OVERRIDE_"
+	// localOverrideFileInterval is how often the local override file is checked for changes
+	localOverrideFileInterval = 2 * time.Second
+)
+
+// localOverrides is a layer of rules that sits above the primary chamber retrieved from the realm server.
+// It is intended for developers running services locally and is disabled unless one of its options is used
+type localOverrides struct {
+	envPrefix string
+	// env maps the normalized rule key to the raw value of its environment variable
+	env map[string]string
+
+	filePath    string
+	mu          sync.RWMutex
+	fileRules   map[string]*OverrideableRule
+	fileModTime time.Time
+	fileSize    int64
+	// active is the sorted keys of the rules overridden when the overrides were last applied
+	active []string
+}
+
+// WithLocalOverrideEnv enables overriding rules of the primary chamber with environment variables.
+// A variable is named prefix followed by the rule key in upper case with every character that is not a letter or digit replaced by an underscore,
+// e.g. REALM_OVERRIDE_DB_POOL_SIZE for the rule "db.pool.size".
+// The value is parsed as the type of the rule it overrides, so only rules that exist in the chamber can be overridden.
+// DefaultLocalOverrideEnvPrefix is used when prefix is empty
+func WithLocalOverrideEnv(prefix string) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		if prefix == "" {
+			prefix = DefaultLocalOverrideEnvPrefix
+		}
+		rc.localOverrideEnvPrefix = prefix
+		return rc
+	})
+}
+
+// WithLocalOverrideFile enables overriding rules of the primary chamber with the rules of a local chamber file.
+// The file has the same format as a chamber and is watched for changes while realm is running.
+// Environment variable overrides take precedence over the file
+func WithLocalOverrideFile(path string) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.localOverrideFile = path
+		return rc
+	})
+}
+
+func newLocalOverrides(envPrefix string, filePath string) (*localOverrides, error) {
+	if envPrefix == "" && filePath == "" {
+		return nil, nil
+	}
+
+	lo := &localOverrides{envPrefix: envPrefix, filePath: filePath, env: make(map[string]string)}
+	if envPrefix != "" {
+		for _, kv := range os.Environ() {
+			name, value, _ := strings.Cut(kv, "=")
+			if key, ok := strings.CutPrefix(name, envPrefix); ok && key != "" {
+				lo.env[normalizeEnvKey(key)] = value
+			}
+		}
+	}
+
+	if filePath != "" {
+		if _, err := lo.reloadFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return lo, nil
+}
+
+// normalizeEnvKey returns the environment variable form of a rule key
+func normalizeEnvKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, key)
+}
+
+// reloadFile reads the override file if it changed since it was last read and reports whether it did
+func (lo *localOverrides) reloadFile() (bool, error) {
+	fi, err := os.Stat(lo.filePath)
+	if err != nil {
+		return false, fmt.Errorf("could not read local override file %q: %w", lo.filePath, err)
+	}
+
+	lo.mu.RLock()
+	unchanged := fi.ModTime().Equal(lo.fileModTime) && fi.Size() == lo.fileSize
+	lo.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	file, err := utils.OpenFile(lo.filePath)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	var c Chamber
+	if err := utils.ReadInterfaceWith(file, &c); err != nil {
+		return false, fmt.Errorf("could not parse local override file %q: %w", lo.filePath, err)
+	}
+
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	lo.fileRules = c.Rules
+	lo.fileModTime = fi.ModTime()
+	lo.fileSize = fi.Size()
+	return true, nil
+}
+
+// apply returns a copy of c with the local overrides applied, the keys of the rules that were overridden
+// and the errors of environment variables that could not be parsed
+func (lo *localOverrides) apply(c *Chamber) (*Chamber, []string, []error) {
+	out := &Chamber{Rules: make(map[string]*OverrideableRule, len(c.Rules))}
+	for k, v := range c.Rules {
+		out.Rules[k] = v
+	}
+
+	var overridden []string
+	var errs []error
+	lo.mu.RLock()
+	for k, v := range lo.fileRules {
+		out.Rules[k] = v
+		overridden = append(overridden, k)
+	}
+	lo.mu.RUnlock()
+
+	for k, rule := range out.Rules {
+		raw, ok := lo.env[normalizeEnvKey(k)]
+		if !ok {
+			continue
+		}
+		envRule, err := parseEnvRule(rule.Type, raw)
+		if err != nil {
+			// an invalid value is ignored rather than failing every refresh
+			errs = append(errs, fmt.Errorf("invalid local override for %q: %w", k, err))
+			continue
+		}
+		out.Rules[k] = envRule
+		if !slices.Contains(overridden, k) {
+			overridden = append(overridden, k)
+		}
+	}
+
+	slices.Sort(overridden)
+	return out, overridden, errs
+}
+
+// setActive records the sorted keys of the overridden rules and reports whether they differ from the previously recorded keys
+func (lo *localOverrides) setActive(overridden []string) bool {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	if slices.Equal(lo.active, overridden) {
+		return false
+	}
+	lo.active = overridden
+	return true
+}
+
+// parseEnvRule parses the raw environment variable value as a rule of the specified type
+func parseEnvRule(ruleType string, raw string) (*OverrideableRule, error) {
+	var value interface{}
+	switch ruleType {
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		value = b
+	case "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, err
+		}
+		value = f
+	case "string":
+		value = raw
+	case "custom":
+		if !json.Valid([]byte(raw)) {
+			return nil, fmt.Errorf("%q is not valid JSON", raw)
+		}
+		msg := json.RawMessage(raw)
+		value = &msg
+	default:
+		return nil, &UnsupportedTypeError{ruleType}
+	}
+	return &OverrideableRule{Rule: &Rule{Type: ruleType, Value: value}}, nil
+}
+
+// watchLocalOverrideFile reapplies the local overrides whenever the override file changes until realm is stopped
+func (rlm *Realm) watchLocalOverrideFile(ctx context.Context) {
+	ticker := time.NewTicker(localOverrideFileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rlm.stopCh:
+			return
+		case <-ticker.C:
+			changed, err := rlm.localOverrides.reloadFile()
+			if err != nil {
+				rlm.logger.ErrorCtx(ctx).Str("error", err.Error()).Msg("could not reload local overrides, keeping previous local overrides")
+				continue
+			}
+			if changed {
+				rlm.logger.WarnCtx(ctx).Str("file", rlm.localOverrides.filePath).Msg("local override file changed")
+				rlm.reapplyLocalOverrides(ctx)
+			}
+		}
+	}
+}
+
+// reapplyLocalOverrides rebuilds the primary chamber from the most recently retrieved one with the current local overrides
+func (rlm *Realm) reapplyLocalOverrides(ctx context.Context) {
+	rlm.refreshMu.Lock()
+	defer rlm.refreshMu.Unlock()
+
+	prev := rlm.getSnapshot()
+	if prev == nil || prev.source == nil {
+		return
+	}
+
+	next := &snapshot{source: prev.source, revision: prev.revision, namespaces: prev.namespaces}
+	next.root = NewChamberEntry(rlm.applyLocalOverrides(ctx, prev.source), rlm.applicationVersion)
+	rlm.swap(prev, next)
+}
+
+// applyLocalOverrides returns c with the local overrides applied, logging the rules that were overridden whenever they change
+func (rlm *Realm) applyLocalOverrides(ctx context.Context, c *Chamber) *Chamber {
+	if rlm.localOverrides == nil {
+		return c
+	}
+	out, overridden, errs := rlm.localOverrides.apply(c)
+	for _, err := range errs {
+		rlm.logger.ErrorCtx(ctx).Str("error", err.Error()).Msg("ignoring local override")
+	}
+	if rlm.localOverrides.setActive(overridden) && len(overridden) > 0 {
+		rlm.logger.WarnCtx(ctx).Strs("rules", overridden).Msg("local overrides are active, these rules are not served from the realm server")
+	}
+	return out
+}
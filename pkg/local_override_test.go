@@ -0,0 +1,82 @@
+package realm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLocalOverrides(t *testing.T) {
+	t.Setenv("TEST_OVERRIDE_DB_POOL_SIZE", "25")
+	t.Setenv("TEST_OVERRIDE_ENABLED", "not a bool")
+
+	file := filepath.Join(t.TempDir(), "overrides.json")
+	if err := os.WriteFile(file, []byte(`{"rules":{"message":{"type":"string","value":"local"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &staticGetter{chamber: `{"rules":{
+		"db.pool.size":{"type":"number","value":10},
+		"enabled":{"type":"boolean","value":true},
+		"message":{"type":"string","value":"remote"}
+	}}`}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/"), WithLocalOverrideEnv("TEST_OVERRIDE_"), WithLocalOverrideFile(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	ctx := context.Background()
+	if v, _ := rlm.Float64(ctx, "db.pool.size", 0); v != 25 {
+		t.Errorf("db.pool.size should be overridden by the environment to 25 but returned %v", v)
+	}
+	if v, _ := rlm.Bool(ctx, "enabled", false); !v {
+		t.Errorf("invalid environment override should be ignored")
+	}
+	if v, _ := rlm.String(ctx, "message", ""); v != "local" {
+		t.Errorf("message should be overridden by the file to %q but returned %q", "local", v)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"rules":{}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// ensure the modification time differs on file systems with coarse timestamps
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(file, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := rlm.localOverrides.reloadFile(); err != nil || !changed {
+		t.Fatalf("file should be reloaded, changed: %v, error: %v", changed, err)
+	}
+	rlm.reapplyLocalOverrides(ctx)
+
+	if v, _ := rlm.String(ctx, "message", ""); v != "remote" {
+		t.Errorf("message should be served from the chamber after the file override is removed but returned %q", v)
+	}
+}
+
+func TestLocalOverridesSetActive(t *testing.T) {
+	lo := &localOverrides{}
+	tests := []struct {
+		name       string
+		overridden []string
+		changed    bool
+	}{
+		{"first overrides", []string{"a", "b"}, true},
+		{"same overrides", []string{"a", "b"}, false},
+		{"override added", []string{"a", "b", "c"}, true},
+		{"overrides removed", nil, true},
+		{"still no overrides", nil, false},
+		{"overrides restored", []string{"a"}, true},
+	}
+	for _, tt := range tests {
+		if changed := lo.setActive(tt.overridden); changed != tt.changed {
+			t.Errorf("%s: expected changed to be %v but returned %v", tt.name, tt.changed, changed)
+		}
+	}
+}
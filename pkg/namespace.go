@@ -0,0 +1,104 @@
+package realm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Namespace provides rule retrievals from one of the additional chambers a Realm is subscribed to.
+// It is created with rlm.In and registered with the WithNamespace option
+type Namespace struct {
+	rlm  *Realm
+	name string
+}
+
+// In returns the Namespace with the specified name
+func (rlm *Realm) In(name string) *Namespace {
+	return &Namespace{rlm: rlm, name: name}
+}
+
+// Name returns the name of the namespace
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+// Chamber returns the namespace's chamber associated with ctx, falling back to the most recently retrieved chamber
+func (ns *Namespace) Chamber(ctx context.Context) (*ChamberEntry, error) {
+	if _, ok := ns.rlm.namespaces[ns.name]; !ok {
+		return nil, &ErrNamespaceNotFound{Name: ns.name}
+	}
+
+	s := snapshotFromContext(ctx)
+	if s == nil {
+		s = ns.rlm.getSnapshot()
+	}
+	if s == nil {
+		return nil, ErrChamberEmpty
+	}
+
+	c, ok := s.namespaces[ns.name]
+	if !ok || c == nil {
+		return nil, ErrChamberEmpty
+	}
+	return c, nil
+}
+
+func (ns *Namespace) evaluate(ctx context.Context, ruleKey string) Evaluation {
+	c, err := ns.Chamber(ctx)
+	if err != nil {
+		return Evaluation{Key: ruleKey, Namespace: ns.name, Reason: ReasonError, Err: err}
+	}
+	return evaluate(ctx, c, ns.name, ruleKey)
+}
+
+// Evaluate evaluates the rule with key from the namespace's chamber associated with ctx.
+// It is observed like every other rule retrieval
+func (ns *Namespace) Evaluate(ctx context.Context, ruleKey string) Evaluation {
+	e := ns.evaluate(ctx, ruleKey)
+	ns.rlm.observe(ctx, e)
+	return e
+}
+
+// Bool retrieves a bool by the key of the rule from the namespace.
+// Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
+func (ns *Namespace) Bool(ctx context.Context, ruleKey string, defaultValue bool) (bool, error) {
+	e := ns.evaluate(ctx, ruleKey)
+	v, err := e.boolValue(defaultValue)
+	ns.rlm.observe(ctx, e)
+	return v, err
+}
+
+// String retrieves a string by the key of the rule from the namespace.
+// Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
+func (ns *Namespace) String(ctx context.Context, ruleKey string, defaultValue string) (string, error) {
+	e := ns.evaluate(ctx, ruleKey)
+	v, err := e.stringValue(defaultValue)
+	ns.rlm.observe(ctx, e)
+	return v, err
+}
+
+// Float64 retrieves a float64 by the key of the rule from the namespace.
+// Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
+func (ns *Namespace) Float64(ctx context.Context, ruleKey string, defaultValue float64) (float64, error) {
+	e := ns.evaluate(ctx, ruleKey)
+	v, err := e.float64Value(defaultValue)
+	ns.rlm.observe(ctx, e)
+	return v, err
+}
+
+// CustomValue retrieves an arbitrary value by the key of the rule from the namespace
+// and unmarshals the value into the custom value v
+func (ns *Namespace) CustomValue(ctx context.Context, ruleKey string, v any) error {
+	e := ns.evaluate(ctx, ruleKey)
+	err := e.customValue(v)
+	ns.rlm.observe(ctx, e)
+	if err != nil && err != ErrChamberEmpty {
+		var nnf *ErrNamespaceNotFound
+		if errors.As(err, &nnf) {
+			return err
+		}
+		return fmt.Errorf("could not convert custom rule %q in namespace %q: %w", ruleKey, ns.name, err)
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package realm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// pathGetter serves a chamber per path
+type pathGetter map[string]*staticGetter
+
+func (g pathGetter) GetChamber(ctx context.Context, path string) (*Chamber, error) {
+	sg, ok := g[path]
+	if !ok {
+		return nil, fmt.Errorf("%v does not exist", path)
+	}
+	return sg.GetChamber(ctx, path)
+}
+
+func TestNamespaceRetrievesFromItsChamber(t *testing.T) {
+	platform := &staticGetter{chamber: `{"rules":{"enabled":{"type":"boolean","value":false}}}`}
+	g := pathGetter{
+		"/service/":  {chamber: `{"rules":{"enabled":{"type":"boolean","value":true}}}`},
+		"/platform/": platform,
+	}
+
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/service/"), WithNamespace("platform", "/platform/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	ctx := rlm.NewContext(context.Background())
+	if v, err := rlm.Bool(ctx, "enabled", false); err != nil || !v {
+		t.Errorf("primary chamber should return true, returned %v with error: %v", v, err)
+	}
+	if v, err := rlm.In("platform").Bool(ctx, "enabled", true); err != nil || v {
+		t.Errorf("platform namespace should return false, returned %v with error: %v", v, err)
+	}
+
+	platform.set(`{"rules":{"enabled":{"type":"boolean","value":true}}}`)
+	if err := rlm.refresh(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, _ := rlm.In("platform").Bool(ctx, "enabled", true); v {
+		t.Errorf("context should keep the snapshot it was created with")
+	}
+	if v, _ := rlm.In("platform").Bool(context.Background(), "enabled", false); !v {
+		t.Errorf("a new context should observe the refreshed namespace")
+	}
+
+	var nnf *ErrNamespaceNotFound
+	if _, err := rlm.In("unknown").Bool(ctx, "enabled", false); !errors.As(err, &nnf) {
+		t.Errorf("unknown namespace should return ErrNamespaceNotFound but returned: %v", err)
+	}
+}
@@ -2,48 +2,92 @@ package realm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/steviebps/realm/api"
-	"github.com/steviebps/realm/client"
 	"github.com/steviebps/realm/helper/logging"
-	"github.com/steviebps/realm/utils"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type Realm struct {
 	applicationVersion string
 	path               string
-	initSync           sync.Once
+	namespaces         map[string]string
+	lifecycleMu        sync.Mutex
+	started            bool
+	stopOnce           sync.Once
 	stopCh             chan struct{}
-	mu                 sync.RWMutex
-	root               *ChamberEntry
-	client             *client.HttpClient
-	pollingInterval    time.Duration
-	logger             *logging.TracedLogger
-	tracer             trace.Tracer
+	// runCtx is the context of realm's goroutines, which is cancelled by Stop
+	runCtx          context.Context
+	cancel          context.CancelFunc
+	wg              sync.WaitGroup
+	ready           chan struct{}
+	readyOnce       sync.Once
+	mu              sync.RWMutex
+	current         *snapshot
+	getter          ChamberGetter
+	observers       []func(context.Context, Evaluation)
+	pollingInterval time.Duration
+	logger          *logging.TracedLogger
+	tracer          trace.Tracer
+	localOverrides  *localOverrides
+	telemetry       *evaluationTelemetry
+	usage           *usageTracker
+	revisionHistory int
+	historyMu       sync.Mutex
+	history         map[string]*snapshot
+	historyOrder    []string
+	refreshMu       sync.Mutex
+	stale           bool
+	subMu           sync.Mutex
+	subscribers     map[int]func(Event)
+	nextSubID       int
 }
 
 type RealmConfig struct {
-	client             *client.HttpClient
+	getter             ChamberGetter
+	observers          []func(context.Context, Evaluation)
 	path               string
 	applicationVersion string
+	// namespaces maps a namespace name to the path of an additional chamber to subscribe to
+	namespaces map[string]string
 	// pollingInterval is how often realm will refetch the chamber from the realm server
 	pollingInterval time.Duration
+	// localOverrideEnvPrefix is the prefix of environment variables that override rules locally
+	localOverrideEnvPrefix string
+	// localOverrideFile is the path of a chamber file that overrides rules locally
+	localOverrideFile string
+	meterProvider     metric.MeterProvider
+	// evaluationEventSampling is the fraction of evaluations added as span events, nil samples every evaluation
+	evaluationEventSampling  *float64
+	evaluationMetricKeyLimit int
+	// usageApplication is the application name usage reports are tagged with, usage is not reported when it is empty
+	usageApplication string
+	usageInterval    time.Duration
+	usageReporter    UsageReporter
+	// revisionHistory is the number of previous revisions kept for pinning, pinning is disabled when it is 0
+	revisionHistory int
 }
 
 const (
 	// DefaultPollingInterval is used as the default polling interval for realm
 	DefaultPollingInterval time.Duration = 15 * time.Minute
+	// startRetryWaitMin and startRetryWaitMax bound the wait between the retrievals of Start
+	startRetryWaitMin = 100 * time.Millisecond
+	startRetryWaitMax = 5 * time.Second
 )
 
+// ChamberGetter retrieves the chamber at the specified path.
+// It allows realm to retrieve chambers from somewhere other than a realm server
+type ChamberGetter interface {
+	GetChamber(ctx context.Context, path string) (*Chamber, error)
+}
+
 type contextKey struct {
 	name string
 }
@@ -52,8 +96,20 @@ var (
 	// RequestContextKey is the context key to use with a WithValue function to associate a root chamber value with a context
 	// such that rule retrievals will be consistent throughout the client's request
 	RequestContextKey = &contextKey{"realm"}
+	// snapshotContextKey is the context key used to associate all subscribed chambers with a context
+	snapshotContextKey = &contextKey{"realm-snapshot"}
 )
 
+// snapshot holds every chamber a Realm is subscribed to as of a single refresh
+type snapshot struct {
+	root       *ChamberEntry
+	namespaces map[string]*ChamberEntry
+	// source is the primary chamber as retrieved, before local overrides were applied
+	source *Chamber
+	// revision is the revision of source
+	revision string
+}
+
 type RealmOption interface {
 	apply(RealmConfig) RealmConfig
 }
@@ -64,9 +120,27 @@ func (fn realmOptionFunc) apply(cfg RealmConfig) RealmConfig {
 	return fn(cfg)
 }
 
-func WithHttpClient(c *client.HttpClient) RealmOption {
+// WithHttpClient retrieves chambers from a realm server with c, which is typically a *client.HttpClient
+func WithHttpClient(c ChamberGetter) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.getter = c
+		return rc
+	})
+}
+
+// WithChamberGetter retrieves chambers with g instead of a realm server client
+func WithChamberGetter(g ChamberGetter) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.getter = g
+		return rc
+	})
+}
+
+// WithEvaluationObserver registers fn to be called after every rule retrieval made with realm.
+// fn is called synchronously on the caller's goroutine and should be cheap
+func WithEvaluationObserver(fn func(ctx context.Context, e Evaluation)) RealmOption {
 	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
-		rc.client = c
+		rc.observers = append(rc.observers, fn)
 		return rc
 	})
 }
@@ -85,6 +159,18 @@ func WithPollingInterval(d time.Duration) RealmOption {
 	})
 }
 
+// WithNamespace subscribes realm to the chamber at path in addition to its primary path.
+// Rules from the chamber can be retrieved with rlm.In(name)
+func WithNamespace(name string, path string) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		if rc.namespaces == nil {
+			rc.namespaces = make(map[string]string)
+		}
+		rc.namespaces[name] = path
+		return rc
+	})
+}
+
 func WithVersion(version string) RealmOption {
 	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
 		rc.applicationVersion = version
@@ -101,7 +187,7 @@ func NewRealm(options ...RealmOption) (*Realm, error) {
 	}
 
 	// TODO: setup sane default
-	if cfg.client == nil {
+	if cfg.getter == nil {
 		return nil, errors.New("client option must not be nil")
 	}
 
@@ -110,106 +196,285 @@ func NewRealm(options ...RealmOption) (*Realm, error) {
 		return nil, errors.New("path must not be empty")
 	}
 
+	for name, path := range cfg.namespaces {
+		if name == "" {
+			return nil, errors.New("namespace name must not be empty")
+		}
+		if path == "" {
+			return nil, fmt.Errorf("path for namespace %q must not be empty", name)
+		}
+	}
+
 	if cfg.pollingInterval <= 0 {
 		cfg.pollingInterval = DefaultPollingInterval
 	}
 
+	lo, err := newLocalOverrides(cfg.localOverrideEnvPrefix, cfg.localOverrideFile)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetry, err := newEvaluationTelemetry(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var usage *usageTracker
+	if cfg.usageApplication != "" {
+		reporter := cfg.usageReporter
+		if reporter == nil {
+			reporter, _ = cfg.getter.(UsageReporter)
+		}
+		if reporter == nil {
+			return nil, errors.New("usage reporting requires a usage reporter or http client")
+		}
+		if cfg.usageInterval <= 0 {
+			cfg.usageInterval = DefaultUsageReportInterval
+		}
+		usage = &usageTracker{reporter: reporter, application: cfg.usageApplication, interval: cfg.usageInterval, counts: make(map[string]map[string]int64)}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
 	return &Realm{
 		tracer:             otel.Tracer("github.com/steviebps/realm"),
 		logger:             logging.NewTracedLogger(),
-		client:             cfg.client,
+		getter:             cfg.getter,
+		observers:          cfg.observers,
 		path:               cfg.path,
+		namespaces:         cfg.namespaces,
 		applicationVersion: cfg.applicationVersion,
 		stopCh:             make(chan struct{}),
+		runCtx:             runCtx,
+		cancel:             cancel,
+		ready:              make(chan struct{}),
 		pollingInterval:    cfg.pollingInterval,
+		localOverrides:     lo,
+		telemetry:          telemetry,
+		usage:              usage,
+		revisionHistory:    cfg.revisionHistory,
+		history:            make(map[string]*snapshot),
 	}, nil
 }
 
-// Start starts realm and initializes the underlying chamber
-func (rlm *Realm) Start() error {
-	var err error
-	ctx := rlm.logger.WithContext(context.Background())
-	rlm.initSync.Do(func() {
-		var chamber *Chamber
-		if chamber, err = rlm.retrieveChamber(ctx, rlm.path); err == nil {
-			rlm.setChamber(chamber)
-		}
-	})
+// Start retrieves every chamber realm is subscribed to and starts polling for changes.
+// When ctx has a deadline, failed retrievals are retried until realm is ready or ctx is done,
+// otherwise the error of the first retrieval is returned. Calling Start on a started realm does nothing
+func (rlm *Realm) Start(ctx context.Context) error {
+	rlm.lifecycleMu.Lock()
+	defer rlm.lifecycleMu.Unlock()
+
+	select {
+	case <-rlm.stopCh:
+		return ErrRealmStopped
+	default:
+	}
+	if rlm.started {
+		return nil
+	}
 
-	if err != nil {
+	ctx = rlm.logger.WithContext(ctx)
+	if lo := rlm.localOverrides; lo != nil {
+		rlm.logger.WarnCtx(ctx).Str("envPrefix", lo.envPrefix).Str("file", lo.filePath).Msg("local overrides are enabled")
+	}
+	if err := rlm.waitForReady(ctx); err != nil {
 		return err
 	}
+	rlm.started = true
 
-	go func() {
-		ticker := time.NewTicker(rlm.pollingInterval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-rlm.stopCh:
-				rlm.logger.InfoCtx(ctx).Msg("shutting down realm")
-				return
-			case <-ticker.C:
-				if chamber, err := rlm.retrieveChamber(ctx, rlm.path); err == nil {
-					rlm.setChamber(chamber)
-				}
-			}
+	runCtx := rlm.logger.WithContext(rlm.runCtx)
+	if rlm.localOverrides != nil && rlm.localOverrides.filePath != "" {
+		rlm.goWithStop(func() { rlm.watchLocalOverrideFile(runCtx) })
+	}
+	if rlm.usage != nil {
+		rlm.goWithStop(func() { rlm.reportUsagePeriodically(runCtx) })
+	}
+	rlm.goWithStop(func() { rlm.poll(runCtx) })
+
+	return nil
+}
+
+// waitForReady performs the initial refresh, retrying it until ctx is done when ctx has a deadline
+func (rlm *Realm) waitForReady(ctx context.Context) error {
+	_, retry := ctx.Deadline()
+	// Stop cancels the retrievals of a concurrent Start
+	ctx, cancel := context.WithCancel(ctx)
+	defer context.AfterFunc(rlm.runCtx, cancel)()
+	defer cancel()
+	wait := startRetryWaitMin
+	for {
+		err := rlm.refresh(ctx, true)
+		if err == nil || !retry {
+			return err
 		}
+
+		rlm.logger.WarnCtx(ctx).Str("error", err.Error()).Str("retryIn", wait.String()).Msg("realm is not ready yet")
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-rlm.stopCh:
+			timer.Stop()
+			return ErrRealmStopped
+		case <-timer.C:
+		}
+		wait = min(wait*2, startRetryWaitMax)
+	}
+}
+
+// goWithStop runs fn in a goroutine that Stop waits for. Callers must hold lifecycleMu
+func (rlm *Realm) goWithStop(fn func()) {
+	rlm.wg.Add(1)
+	go func() {
+		defer rlm.wg.Done()
+		fn()
 	}()
+}
 
-	return nil
+// poll refreshes every chamber each polling interval until realm is stopped
+func (rlm *Realm) poll(ctx context.Context) {
+	ticker := time.NewTicker(rlm.pollingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rlm.stopCh:
+			rlm.logger.InfoCtx(ctx).Msg("shutting down realm")
+			return
+		case <-ticker.C:
+			rlm.refresh(ctx, false)
+		}
+	}
+}
+
+// Ready returns a channel that is closed once realm has retrieved every chamber it is subscribed to
+func (rlm *Realm) Ready() <-chan struct{} {
+	return rlm.ready
 }
 
-// Stop stops realm and flushes any pending tasks
-func (rlm *Realm) Stop() {
-	close(rlm.stopCh)
+// Refresh immediately retrieves every chamber realm is subscribed to
+func (rlm *Realm) Refresh(ctx context.Context) error {
+	return rlm.refresh(rlm.logger.WithContext(ctx), false)
+}
+
+// Stop stops realm, cancelling in-flight retrievals, and waits for its goroutines to exit, including the final usage report,
+// or for ctx to be done. Stop can be called more than once and a stopped realm cannot be started again
+func (rlm *Realm) Stop(ctx context.Context) error {
+	rlm.stopOnce.Do(func() {
+		close(rlm.stopCh)
+		rlm.cancel()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		// wait for a concurrent Start to return so that it does not start goroutines after waiting
+		rlm.lifecycleMu.Lock()
+		rlm.lifecycleMu.Unlock()
+		rlm.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (rlm *Realm) retrieveChamber(ctx context.Context, path string) (*Chamber, error) {
 	ctx, span := rlm.tracer.Start(ctx, "retrieveChamber", trace.WithAttributes(attribute.String("realm.path", path)))
 	defer span.End()
 
-	logger := rlm.logger
-	client := rlm.client
-
-	res, err := client.PerformRequest(ctx, "GET", strings.TrimPrefix(path, "/"), nil)
+	c, err := rlm.getter.GetChamber(ctx, path)
 	if err != nil {
-		logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not perform request for getting: %q, %s", path, err.Error()))
+		rlm.logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not get chamber %q: %s", path, err.Error()))
 		return nil, err
 	}
-	defer res.Body.Close()
+	return c, nil
+}
+
+// refresh retrieves the primary chamber and every namespaced chamber and swaps them in together
+// so that a context created afterwards observes all of them from the same refresh.
+// When strict is false, chambers that could not be retrieved keep their previous value
+func (rlm *Realm) refresh(ctx context.Context, strict bool) error {
+	rlm.refreshMu.Lock()
+	defer rlm.refreshMu.Unlock()
+
+	prev := rlm.getSnapshot()
+	next := &snapshot{namespaces: make(map[string]*ChamberEntry, len(rlm.namespaces))}
+
+	var errs []error
+	chamber, err := rlm.retrieveChamber(ctx, rlm.path)
+	if err == nil {
+		next.source = chamber
+		next.revision = chamber.Revision()
+		next.root = NewChamberEntry(rlm.applyLocalOverrides(ctx, chamber), rlm.applicationVersion)
+	} else {
+		errs = append(errs, err)
+		if prev != nil {
+			next.root = prev.root
+			next.source = prev.source
+			next.revision = prev.revision
+		}
+	}
 
-	var httpRes api.HTTPErrorAndDataResponse
-	if err := utils.ReadInterfaceWith(res.Body, &httpRes); err != nil {
-		logger.ErrorCtx(ctx).Str("error", err.Error()).Msg(fmt.Sprintf("could not read response for getting: %q", path))
-		return nil, err
+	for name, path := range rlm.namespaces {
+		chamber, err := rlm.retrieveChamber(ctx, path)
+		if err == nil {
+			next.namespaces[name] = NewChamberEntry(chamber, rlm.applicationVersion)
+			continue
+		}
+		errs = append(errs, fmt.Errorf("namespace %q: %w", name, err))
+		if prev != nil {
+			if entry, ok := prev.namespaces[name]; ok {
+				next.namespaces[name] = entry
+			}
+		}
 	}
 
-	if len(httpRes.Errors) > 0 {
-		logger.ErrorCtx(ctx).Msg(fmt.Sprintf("could not get %q: %s", path, httpRes.Errors))
-		return nil, fmt.Errorf("%s", httpRes.Errors)
+	err = errors.Join(errs...)
+	if err != nil && strict {
+		return err
 	}
 
-	var c Chamber
-	err = json.Unmarshal(httpRes.Data, &c)
+	var events []Event
 	if err != nil {
-		logger.ErrorCtx(ctx).Str("error", err.Error()).Msg(fmt.Sprintf("could not unmarshal chamber from response for getting: %q", path))
-		return nil, err
+		rlm.logger.ErrorCtx(ctx).Str("error", err.Error()).Msg("could not refresh all chambers, serving previously retrieved rules")
+		rlm.stale = true
+		events = append(events, Event{Type: EventStale, Err: err})
+	} else if rlm.stale {
+		rlm.stale = false
+		events = append(events, Event{Type: EventReady})
 	}
-
-	return &c, nil
+	rlm.swap(prev, next, events...)
+	if err == nil {
+		rlm.readyOnce.Do(func() { close(rlm.ready) })
+	}
+	return err
 }
 
-func (rlm *Realm) setChamber(c *Chamber) {
-	entry := NewChamberEntry(c, rlm.applicationVersion)
+// swap replaces the current snapshot with next and emits the resulting change events followed by events.
+// Callers must hold refreshMu
+func (rlm *Realm) swap(prev *snapshot, next *snapshot, events ...Event) {
 	rlm.mu.Lock()
-	defer rlm.mu.Unlock()
-	rlm.root = entry
+	rlm.current = next
+	rlm.mu.Unlock()
+	rlm.remember(next)
+
+	rlm.emit(append(changeEvents(prev, next), events...)...)
 }
 
-func (rlm *Realm) getChamber() *ChamberEntry {
+func (rlm *Realm) getSnapshot() *snapshot {
 	rlm.mu.RLock()
 	defer rlm.mu.RUnlock()
-	return rlm.root
+	return rlm.current
+}
+
+func (rlm *Realm) getChamber() *ChamberEntry {
+	s := rlm.getSnapshot()
+	if s == nil {
+		return nil
+	}
+	return s.root
 }
 
 func chamberFromContext(ctx context.Context) *ChamberEntry {
@@ -220,6 +485,12 @@ func chamberFromContext(ctx context.Context) *ChamberEntry {
 	return c
 }
 
+// Chamber returns the primary chamber associated with ctx, falling back to the most recently retrieved chamber.
+// Will return nil if realm has not retrieved a chamber yet
+func (rlm *Realm) Chamber(ctx context.Context) *ChamberEntry {
+	return rlm.chamberFromContext(ctx)
+}
+
 func (rlm *Realm) chamberFromContext(ctx context.Context) *ChamberEntry {
 	c := chamberFromContext(ctx)
 	if c != nil {
@@ -228,52 +499,88 @@ func (rlm *Realm) chamberFromContext(ctx context.Context) *ChamberEntry {
 	return rlm.getChamber()
 }
 
+func snapshotFromContext(ctx context.Context) *snapshot {
+	s, ok := ctx.Value(snapshotContextKey).(*snapshot)
+	if !ok {
+		return nil
+	}
+	return s
+}
+
+// NewContext returns a copy of ctx associated with the current chambers
+// such that rule retrievals, including those of namespaces, are consistent for the lifetime of ctx
 func (rlm *Realm) NewContext(ctx context.Context) context.Context {
-	c := rlm.getChamber()
+	s := rlm.pinnedSnapshot(ctx)
+	var c *ChamberEntry
+	if s != nil {
+		c = s.root
+	}
 	ctx = context.WithValue(ctx, RequestContextKey, c)
-	return ctx
+	ctx = context.WithValue(ctx, snapshotContextKey, s)
+	return rlm.withRevisionBaggage(ctx, s)
+}
+
+// evaluate evaluates the rule from c, which is nil if no chamber has been retrieved
+func evaluate(ctx context.Context, c *ChamberEntry, namespace string, ruleKey string) Evaluation {
+	if c == nil {
+		return Evaluation{Key: ruleKey, Namespace: namespace, Reason: ReasonError, Err: ErrChamberEmpty}
+	}
+	e := c.EvaluateAtVersion(ruleKey, versionFromContext(ctx, c))
+	e.Namespace = namespace
+	return e
+}
+
+func (rlm *Realm) observe(ctx context.Context, e Evaluation) {
+	rlm.telemetry.record(ctx, rlm.evaluationSetID(e), e)
+	rlm.recordUsage(e)
+	for _, fn := range rlm.observers {
+		fn(ctx, e)
+	}
+}
+
+// Evaluate evaluates the rule with key from the chamber associated with ctx.
+// It is observed like every other rule retrieval, e.g. for integrations that convert the evaluation themselves
+func (rlm *Realm) Evaluate(ctx context.Context, ruleKey string) Evaluation {
+	e := evaluate(ctx, rlm.chamberFromContext(ctx), "", ruleKey)
+	rlm.observe(ctx, e)
+	return e
 }
 
 // Bool retrieves a bool by the key of the rule.
 // Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
 func (rlm *Realm) Bool(ctx context.Context, ruleKey string, defaultValue bool) (bool, error) {
-	c := rlm.chamberFromContext(ctx)
-	if c == nil {
-		return defaultValue, ErrChamberEmpty
-	}
-	return c.BoolValue(ruleKey, defaultValue)
+	e := evaluate(ctx, rlm.chamberFromContext(ctx), "", ruleKey)
+	v, err := e.boolValue(defaultValue)
+	rlm.observe(ctx, e)
+	return v, err
 }
 
 // String retrieves a string by the key of the rule.
 // Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
 func (rlm *Realm) String(ctx context.Context, ruleKey string, defaultValue string) (string, error) {
-	c := rlm.chamberFromContext(ctx)
-	if c == nil {
-		return defaultValue, ErrChamberEmpty
-	}
-	return c.StringValue(ruleKey, defaultValue)
+	e := evaluate(ctx, rlm.chamberFromContext(ctx), "", ruleKey)
+	v, err := e.stringValue(defaultValue)
+	rlm.observe(ctx, e)
+	return v, err
 }
 
 // Float64 retrieves a float64 by the key of the rule.
 // Returns the default value if it does not exist and an error if the chamber is empty or could not be converted
 func (rlm *Realm) Float64(ctx context.Context, ruleKey string, defaultValue float64) (float64, error) {
-	c := rlm.chamberFromContext(ctx)
-	if c == nil {
-		return defaultValue, ErrChamberEmpty
-	}
-	return c.Float64Value(ruleKey, defaultValue)
+	e := evaluate(ctx, rlm.chamberFromContext(ctx), "", ruleKey)
+	v, err := e.float64Value(defaultValue)
+	rlm.observe(ctx, e)
+	return v, err
 }
 
 // CustomValue retrieves an arbitrary value by the key of the rule
 // and unmarshals the value into the custom value v
 func (rlm *Realm) CustomValue(ctx context.Context, ruleKey string, v any) error {
-	c := rlm.chamberFromContext(ctx)
-	if c == nil {
-		return ErrChamberEmpty
-	}
-	err := c.CustomValue(ruleKey, v)
-	if err != nil {
+	e := evaluate(ctx, rlm.chamberFromContext(ctx), "", ruleKey)
+	err := e.customValue(v)
+	rlm.observe(ctx, e)
+	if err != nil && err != ErrChamberEmpty {
 		return fmt.Errorf("could not convert custom rule %q: %w", ruleKey, err)
 	}
-	return nil
+	return err
 }
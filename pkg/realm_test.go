@@ -0,0 +1,195 @@
+package realm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyGetter fails the first failures retrievals, or every retrieval when failures is negative
+type flakyGetter struct {
+	staticGetter
+	failMu   sync.Mutex
+	failures int
+}
+
+func (g *flakyGetter) GetChamber(ctx context.Context, path string) (*Chamber, error) {
+	g.failMu.Lock()
+	if g.failures != 0 {
+		g.failures--
+		g.failMu.Unlock()
+		return nil, errors.New("unavailable")
+	}
+	g.failMu.Unlock()
+	return g.staticGetter.GetChamber(ctx, path)
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestStartReadiness(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		timeout  time.Duration
+		wantErr  error
+	}{
+		{"ready", 0, 0, nil},
+		{"without deadline", 1, 0, errors.New("unavailable")},
+		{"retried until ready", 2, 5 * time.Second, nil},
+		{"deadline exceeded", -1, 200 * time.Millisecond, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &flakyGetter{staticGetter: staticGetter{chamber: `{"rules":{"enabled":{"type":"boolean","value":true}}}`}, failures: tt.failures}
+			rlm, err := NewRealm(WithChamberGetter(g), WithPath("app"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rlm.Stop(context.Background())
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			err = rlm.Start(ctx)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Start() = %v, expected no error", err)
+				}
+				if !isClosed(rlm.Ready()) {
+					t.Error("expected realm to be ready")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("Start() expected an error")
+			}
+			if errors.Is(tt.wantErr, context.DeadlineExceeded) && !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Start() = %v, expected %v", err, tt.wantErr)
+			}
+			if isClosed(rlm.Ready()) {
+				t.Error("expected realm not to be ready")
+			}
+		})
+	}
+}
+
+func TestStartAfterFailedStart(t *testing.T) {
+	g := &flakyGetter{staticGetter: staticGetter{chamber: `{"rules":{}}`}, failures: 1}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	if err := rlm.Start(context.Background()); err == nil {
+		t.Fatal("expected the first Start to fail")
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatalf("expected the second Start to succeed: %v", err)
+	}
+	if !isClosed(rlm.Ready()) {
+		t.Error("expected realm to be ready")
+	}
+}
+
+func TestStop(t *testing.T) {
+	g := &staticGetter{chamber: `{"rules":{}}`}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("app"), WithPollingInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatalf("expected starting a started realm to do nothing: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for range 2 {
+		if err := rlm.Stop(ctx); err != nil {
+			t.Fatalf("Stop() = %v", err)
+		}
+	}
+	if err := rlm.Start(context.Background()); !errors.Is(err, ErrRealmStopped) {
+		t.Errorf("Start() = %v, expected %v", err, ErrRealmStopped)
+	}
+}
+
+func TestStopInterruptsStart(t *testing.T) {
+	g := &flakyGetter{staticGetter: staticGetter{chamber: `{"rules":{}}`}, failures: -1}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- rlm.Start(ctx) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if err := rlm.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrRealmStopped) && !errors.Is(err, context.Canceled) {
+			t.Errorf("Start() = %v, expected it to be stopped", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Stop")
+	}
+}
+
+// blockingGetter blocks until released, ignoring cancellation
+type blockingGetter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *blockingGetter) GetChamber(ctx context.Context, path string) (*Chamber, error) {
+	close(g.started)
+	<-g.release
+	return &Chamber{}, nil
+}
+
+func TestStopHonoursContextDuringStart(t *testing.T) {
+	g := &blockingGetter{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(g.release)
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	go rlm.Start(context.Background())
+	<-g.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- rlm.Stop(ctx) }()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Stop() = %v, expected %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return when its context was done")
+	}
+}
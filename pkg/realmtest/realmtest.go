@@ -0,0 +1,258 @@
+// Package realmtest provides utilities for testing code that uses realm
+// without running a realm server.
+package realmtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	realm "github.com/steviebps/realm/pkg"
+)
+
+// Path is the path of the primary chamber of a Realm created with New
+const Path = "/"
+
+// Fake holds the in-memory chambers of a Realm and records which rules were read from it
+type Fake struct {
+	mu       sync.Mutex
+	chambers map[string]*realm.Chamber
+	reads    map[string]int
+	rlm      *realm.Realm
+}
+
+var (
+	_ realm.ChamberGetter = (*Fake)(nil)
+)
+
+// New returns a started Fake whose Realm serves c as its primary chamber.
+// The Realm is stopped when the test completes
+func New(t testing.TB, c *realm.Chamber, options ...realm.RealmOption) *Fake {
+	t.Helper()
+	return NewWithChambers(t, map[string]*realm.Chamber{Path: c}, options...)
+}
+
+// NewWithChambers returns a started Fake whose Realm serves the chambers keyed by their path.
+// Namespaces can be subscribed to by passing realm.WithNamespace with one of the paths
+func NewWithChambers(t testing.TB, chambers map[string]*realm.Chamber, options ...realm.RealmOption) *Fake {
+	t.Helper()
+
+	f := &Fake{
+		chambers: make(map[string]*realm.Chamber, len(chambers)),
+		reads:    make(map[string]int),
+	}
+	for p, c := range chambers {
+		f.chambers[p] = copyChamber(t, c)
+	}
+
+	opts := []realm.RealmOption{realm.WithPath(Path)}
+	opts = append(opts, options...)
+	opts = append(opts, realm.WithChamberGetter(f), realm.WithEvaluationObserver(f.observe))
+
+	rlm, err := realm.NewRealm(opts...)
+	if err != nil {
+		t.Fatalf("could not create realm: %v", err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatalf("could not start realm: %v", err)
+	}
+	t.Cleanup(func() { rlm.Stop(context.Background()) })
+
+	f.rlm = rlm
+	return f
+}
+
+// Realm returns the Realm backed by the fake
+func (f *Fake) Realm() *realm.Realm {
+	return f.rlm
+}
+
+// GetChamber returns a copy of the chamber at path
+func (f *Fake) GetChamber(ctx context.Context, path string) (*realm.Chamber, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c, ok := f.chambers[path]
+	if !ok {
+		return nil, fmt.Errorf("%v does not exist", path)
+	}
+	return roundTrip(c)
+}
+
+// SetRule sets the rule at key in the primary chamber and refreshes the Realm
+func (f *Fake) SetRule(t testing.TB, key string, rule *realm.OverrideableRule) {
+	t.Helper()
+	f.SetRuleAt(t, Path, key, rule)
+}
+
+// SetRuleAt sets the rule at key in the chamber at path and refreshes the Realm
+func (f *Fake) SetRuleAt(t testing.TB, path string, key string, rule *realm.OverrideableRule) {
+	t.Helper()
+
+	f.mu.Lock()
+	c, ok := f.chambers[path]
+	if !ok {
+		c = &realm.Chamber{Rules: map[string]*realm.OverrideableRule{}}
+		f.chambers[path] = c
+	}
+	c.Rules[key] = rule
+	f.mu.Unlock()
+
+	f.refresh(t)
+}
+
+// SetBool sets a boolean rule at key in the primary chamber and refreshes the Realm
+func (f *Fake) SetBool(t testing.TB, key string, v bool) {
+	t.Helper()
+	f.SetRule(t, key, &realm.OverrideableRule{Rule: &realm.Rule{Type: "boolean", Value: v}})
+}
+
+// SetString sets a string rule at key in the primary chamber and refreshes the Realm
+func (f *Fake) SetString(t testing.TB, key string, v string) {
+	t.Helper()
+	f.SetRule(t, key, &realm.OverrideableRule{Rule: &realm.Rule{Type: "string", Value: v}})
+}
+
+// SetFloat64 sets a number rule at key in the primary chamber and refreshes the Realm
+func (f *Fake) SetFloat64(t testing.TB, key string, v float64) {
+	t.Helper()
+	f.SetRule(t, key, &realm.OverrideableRule{Rule: &realm.Rule{Type: "number", Value: v}})
+}
+
+// SetCustom sets a custom rule at key in the primary chamber to the JSON encoding of v and refreshes the Realm
+func (f *Fake) SetCustom(t testing.TB, key string, v any) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("could not marshal custom rule %q: %v", key, err)
+	}
+	raw := json.RawMessage(b)
+	f.SetRule(t, key, &realm.OverrideableRule{Rule: &realm.Rule{Type: "custom", Value: &raw}})
+}
+
+// Toggle flips the boolean rule at key in the primary chamber and refreshes the Realm
+func (f *Fake) Toggle(t testing.TB, key string) {
+	t.Helper()
+
+	f.mu.Lock()
+	var rule *realm.OverrideableRule
+	if c, ok := f.chambers[Path]; ok {
+		rule = c.Rules[key]
+	}
+	f.mu.Unlock()
+	if rule == nil {
+		t.Fatalf("cannot toggle %q: rule does not exist", key)
+	}
+	v, ok := rule.Value.(bool)
+	if !ok {
+		t.Fatalf("cannot toggle %q: rule is of type %q", key, rule.Type)
+	}
+	f.SetBool(t, key, !v)
+}
+
+// DeleteRule removes the rule at key from the primary chamber and refreshes the Realm
+func (f *Fake) DeleteRule(t testing.TB, key string) {
+	t.Helper()
+
+	f.mu.Lock()
+	if c, ok := f.chambers[Path]; ok {
+		delete(c.Rules, key)
+	}
+	f.mu.Unlock()
+
+	f.refresh(t)
+}
+
+// Reads returns the sorted keys of every rule read through the Realm.
+// Keys read from a namespace are prefixed with the namespace name and a colon
+func (f *Fake) Reads() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.reads))
+	for k := range f.reads {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ReadCount returns the number of times the rule at key was read through the Realm
+func (f *Fake) ReadCount(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads[key]
+}
+
+// ResetReads forgets every read recorded so far
+func (f *Fake) ResetReads() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.reads)
+}
+
+// AssertRead fails the test if any of the keys were not read through the Realm
+func (f *Fake) AssertRead(t testing.TB, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		if f.ReadCount(k) == 0 {
+			t.Errorf("expected %q to be read but it was not, read: %q", k, f.Reads())
+		}
+	}
+}
+
+// AssertNotRead fails the test if any of the keys were read through the Realm
+func (f *Fake) AssertNotRead(t testing.TB, keys ...string) {
+	t.Helper()
+	for _, k := range keys {
+		if n := f.ReadCount(k); n > 0 {
+			t.Errorf("expected %q not to be read but it was read %d time(s)", k, n)
+		}
+	}
+}
+
+func (f *Fake) observe(ctx context.Context, e realm.Evaluation) {
+	key := e.Key
+	if e.Namespace != "" {
+		key = e.Namespace + ":" + key
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads[key]++
+}
+
+func (f *Fake) refresh(t testing.TB) {
+	t.Helper()
+	if err := f.rlm.Refresh(context.Background()); err != nil {
+		t.Fatalf("could not refresh realm: %v", err)
+	}
+}
+
+func copyChamber(t testing.TB, c *realm.Chamber) *realm.Chamber {
+	t.Helper()
+	if c == nil {
+		return &realm.Chamber{Rules: map[string]*realm.OverrideableRule{}}
+	}
+	cp, err := roundTrip(c)
+	if err != nil {
+		t.Fatalf("invalid chamber: %v", err)
+	}
+	return cp
+}
+
+// roundTrip copies c through its JSON encoding so that it is validated the same way as chambers from a realm server
+func roundTrip(c *realm.Chamber) (*realm.Chamber, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var cp realm.Chamber
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
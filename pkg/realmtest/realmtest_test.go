@@ -0,0 +1,64 @@
+package realmtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/steviebps/realm/client"
+	realm "github.com/steviebps/realm/pkg"
+)
+
+func TestFakeFlipsRulesAndRecordsReads(t *testing.T) {
+	f := NewWithChambers(t, map[string]*realm.Chamber{Path: nil, "/platform/": nil}, realm.WithNamespace("platform", "/platform/"))
+	f.SetBool(t, "enabled", false)
+	f.SetRuleAt(t, "/platform/", "region", &realm.OverrideableRule{Rule: &realm.Rule{Type: "string", Value: "us"}})
+
+	rlm := f.Realm()
+	ctx := context.Background()
+	if v, err := rlm.Bool(ctx, "enabled", true); err != nil || v {
+		t.Errorf("enabled should be false, returned %v with error: %v", v, err)
+	}
+
+	f.Toggle(t, "enabled")
+	if v, err := rlm.Bool(ctx, "enabled", false); err != nil || !v {
+		t.Errorf("enabled should be true after toggling, returned %v with error: %v", v, err)
+	}
+
+	if v, err := rlm.In("platform").String(ctx, "region", ""); err != nil || v != "us" {
+		t.Errorf("region should be %q, returned %q with error: %v", "us", v, err)
+	}
+
+	f.AssertRead(t, "enabled", "platform:region")
+	f.AssertNotRead(t, "missing")
+	if n := f.ReadCount("enabled"); n != 2 {
+		t.Errorf("enabled should be read 2 times but was read %d times", n)
+	}
+
+	f.DeleteRule(t, "enabled")
+	if _, err := rlm.Bool(ctx, "enabled", false); err == nil {
+		t.Errorf("deleted rule should return an error")
+	}
+}
+
+func TestServerServesChambers(t *testing.T) {
+	srv := NewServer(t, map[string]*realm.Chamber{
+		"/service/": {Rules: map[string]*realm.OverrideableRule{"enabled": {Rule: &realm.Rule{Type: "boolean", Value: true}}}},
+	})
+
+	c, err := client.NewHttpClient(&client.HttpClientConfig{Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rlm, err := realm.NewRealm(realm.WithHttpClient(c), realm.WithPath("/service/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	if v, err := rlm.Bool(context.Background(), "enabled", false); err != nil || !v {
+		t.Errorf("enabled should be true, returned %v with error: %v", v, err)
+	}
+}
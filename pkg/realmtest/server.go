@@ -0,0 +1,51 @@
+package realmtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	realmhttp "github.com/steviebps/realm/http"
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/pkg/storage"
+)
+
+// NewServer starts a realm server backed by in-memory storage with an empty root chamber
+// and the chambers keyed by their path. The server is closed when the test completes
+func NewServer(t testing.TB, chambers map[string]*realm.Chamber) *httptest.Server {
+	t.Helper()
+
+	ctx := context.Background()
+	stg, err := storage.NewInmemStorage(nil)
+	if err != nil {
+		t.Fatalf("could not create storage: %v", err)
+	}
+	stg, err = storage.NewInheritableStorage(stg)
+	if err != nil {
+		t.Fatalf("could not create storage: %v", err)
+	}
+
+	entries := map[string]*realm.Chamber{"/": {Rules: map[string]*realm.OverrideableRule{}}}
+	for p, c := range chambers {
+		entries[p] = c
+	}
+	for p, c := range entries {
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("could not marshal chamber %q: %v", p, err)
+		}
+		if err := stg.Put(ctx, storage.StorageEntry{Key: p, Value: b}); err != nil {
+			t.Fatalf("could not store chamber %q: %v", p, err)
+		}
+	}
+
+	handler, err := realmhttp.NewHandler(ctx, realmhttp.HandlerConfig{Storage: stg})
+	if err != nil {
+		t.Fatalf("could not create handler: %v", err)
+	}
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
@@ -0,0 +1,147 @@
+package realm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/steviebps/realm/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// RevisionBaggageKey is the OpenTelemetry baggage member that carries the revision of the primary chamber a request was evaluated with
+	RevisionBaggageKey = "realm.revision"
+	// PathBaggageKey is the OpenTelemetry baggage member that carries the path of the primary chamber a request was evaluated with
+	PathBaggageKey = "realm.path"
+	// DefaultRevisionHistory is the default number of previous revisions kept for pinning with WithRevisionPinning
+	DefaultRevisionHistory = 8
+)
+
+// Revision returns a hash of the chamber's rules that changes whenever any of them changes
+func (c *Chamber) Revision() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// WithRevisionPinning makes contexts created with NewContext use the caller's revision of the primary chamber
+// when it is propagated through OpenTelemetry baggage and is one of the last history revisions realm retrieved.
+// DefaultRevisionHistory is used when history is not positive
+func WithRevisionPinning(history int) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		if history <= 0 {
+			history = DefaultRevisionHistory
+		}
+		rc.revisionHistory = history
+		return rc
+	})
+}
+
+// Revision returns the revision of the primary chamber associated with ctx, or of the current one if there is none.
+// It is empty if no chamber has been retrieved
+func (rlm *Realm) Revision(ctx context.Context) string {
+	s := snapshotFromContext(ctx)
+	if s == nil {
+		s = rlm.getSnapshot()
+	}
+	if s == nil {
+		return ""
+	}
+	return s.revision
+}
+
+// revisionPath normalizes a chamber path so that paths of the same chamber compare equal across services
+func revisionPath(p string) string {
+	return utils.EnsureTrailingSlash("/" + strings.TrimPrefix(p, "/"))
+}
+
+// callerRevision returns the revision of the primary chamber propagated by the caller if it is of the same path as realm's
+func (rlm *Realm) callerRevision(ctx context.Context) string {
+	b := baggage.FromContext(ctx)
+	rev := b.Member(RevisionBaggageKey).Value()
+	if rev == "" || revisionPath(b.Member(PathBaggageKey).Value()) != revisionPath(rlm.path) {
+		return ""
+	}
+	return rev
+}
+
+// pinnedSnapshot returns the snapshot to associate with a context: the caller's revision when pinning is enabled and it is cached,
+// otherwise the current snapshot. A caller revision that differs from the returned snapshot's is reported as a mismatch
+func (rlm *Realm) pinnedSnapshot(ctx context.Context) *snapshot {
+	s := rlm.getSnapshot()
+	caller := rlm.callerRevision(ctx)
+	if s == nil || caller == "" || caller == s.revision {
+		return s
+	}
+
+	if rlm.revisionHistory > 0 {
+		rlm.historyMu.Lock()
+		pinned, ok := rlm.history[caller]
+		rlm.historyMu.Unlock()
+		if ok {
+			rlm.logger.DebugCtx(ctx).Str("revision", caller).Str("current", s.revision).Msg("pinned to the caller's revision")
+			return pinned
+		}
+	}
+
+	attrs := attribute.NewSet(attribute.String("realm.path", revisionPath(rlm.path)))
+	rlm.telemetry.revisionMismatches.Add(ctx, 1, metric.WithAttributeSet(attrs))
+	trace.SpanFromContext(ctx).AddEvent("realm.revision_mismatch", trace.WithAttributes(
+		attribute.String("realm.revision", s.revision),
+		attribute.String("realm.caller_revision", caller),
+	))
+	rlm.logger.DebugCtx(ctx).Str("revision", s.revision).Str("callerRevision", caller).Msg("caller evaluated a different revision of the chamber")
+	return s
+}
+
+// withRevisionBaggage returns a copy of ctx whose baggage carries the revision of s so that it propagates to downstream services
+func (rlm *Realm) withRevisionBaggage(ctx context.Context, s *snapshot) context.Context {
+	if s == nil || s.revision == "" {
+		return ctx
+	}
+	rev, err := baggage.NewMemberRaw(RevisionBaggageKey, s.revision)
+	if err != nil {
+		return ctx
+	}
+	path, err := baggage.NewMemberRaw(PathBaggageKey, revisionPath(rlm.path))
+	if err != nil {
+		return ctx
+	}
+
+	b := baggage.FromContext(ctx)
+	if b, err = b.SetMember(rev); err != nil {
+		return ctx
+	}
+	if b, err = b.SetMember(path); err != nil {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, b)
+}
+
+// remember keeps the snapshot as one of the revisions that can be pinned to
+func (rlm *Realm) remember(s *snapshot) {
+	if rlm.revisionHistory <= 0 || s == nil || s.revision == "" {
+		return
+	}
+
+	rlm.historyMu.Lock()
+	defer rlm.historyMu.Unlock()
+	if _, ok := rlm.history[s.revision]; !ok {
+		rlm.historyOrder = append(rlm.historyOrder, s.revision)
+	}
+	// the latest snapshot of a revision is kept so local overrides that were reapplied are used
+	rlm.history[s.revision] = s
+	for len(rlm.historyOrder) > rlm.revisionHistory {
+		delete(rlm.history, rlm.historyOrder[0])
+		rlm.historyOrder = rlm.historyOrder[1:]
+	}
+}
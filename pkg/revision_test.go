@@ -0,0 +1,83 @@
+package realm
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRevisionPropagation(t *testing.T) {
+	v1 := `{"rules":{"message":{"type":"string","value":"v1"}}}`
+	v2 := `{"rules":{"message":{"type":"string","value":"v2"}}}`
+
+	upstream, err := NewRealm(WithChamberGetter(&staticGetter{chamber: v1}), WithPath("app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := upstream.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Stop(context.Background())
+
+	upCtx := upstream.NewContext(context.Background())
+	rev := upstream.Revision(upCtx)
+	if rev == "" || baggage.FromContext(upCtx).Member(RevisionBaggageKey).Value() != rev {
+		t.Fatalf("context should carry the revision %q in its baggage", rev)
+	}
+
+	tests := []struct {
+		name       string
+		options    []RealmOption
+		expected   string
+		mismatches int64
+	}{
+		{"reports mismatches", nil, "v2", 1},
+		{"pins to the caller's revision", []RealmOption{WithRevisionPinning(0)}, "v1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			g := &staticGetter{chamber: v1}
+			opts := append([]RealmOption{WithChamberGetter(g), WithPath("/app/"), WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))}, tt.options...)
+			downstream, err := NewRealm(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := downstream.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer downstream.Stop(context.Background())
+
+			g.set(v2)
+			if err := downstream.Refresh(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := downstream.NewContext(upCtx)
+			if v, _ := downstream.String(ctx, "message", ""); v != tt.expected {
+				t.Errorf("expected %q but returned %q", tt.expected, v)
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatal(err)
+			}
+			var mismatches int64
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "realm.revision.mismatches" {
+						for _, dp := range sum.DataPoints {
+							mismatches += dp.Value
+						}
+					}
+				}
+			}
+			if mismatches != tt.mismatches {
+				t.Errorf("expected %d mismatches but recorded %d", tt.mismatches, mismatches)
+			}
+		})
+	}
+}
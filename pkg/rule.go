@@ -119,17 +119,24 @@ func (t *OverrideableRule) UnmarshalJSON(b []byte) error {
 // ValueAtVersion returns the value at the given version.
 // Will return default value if version is empty string or no override is present for the specified version
 func (t *OverrideableRule) ValueAtVersion(version string) interface{} {
-	v := t.Value
-	if version != "" {
-		for _, override := range t.Overrides {
-			if semver.Compare(override.MinimumVersion, version) <= 0 && semver.Compare(override.MaximumVersion, version) >= 0 {
-				v = override.Value
-				break
-			}
-		}
+	if override := t.OverrideAtVersion(version); override != nil {
+		return override.Value
 	}
+	return t.Value
+}
 
-	return v
+// OverrideAtVersion returns the override that applies to the given version.
+// Will return nil if version is empty string or no override is present for the specified version
+func (t *OverrideableRule) OverrideAtVersion(version string) *Override {
+	if version == "" {
+		return nil
+	}
+	for _, override := range t.Overrides {
+		if semver.Compare(override.MinimumVersion, version) <= 0 && semver.Compare(override.MaximumVersion, version) >= 0 {
+			return override
+		}
+	}
+	return nil
 }
 
 // StringValue retrieves a string value of the rule
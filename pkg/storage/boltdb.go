@@ -23,6 +23,7 @@ type BoltStorage struct {
 
 var (
 	_ Storage = (*BoltStorage)(nil)
+	_ Updater = (*BoltStorage)(nil)
 )
 
 // NewBoltStorage creates a new BoltDB storage backend
@@ -217,6 +218,53 @@ func (b *BoltStorage) List(ctx context.Context, prefix string) ([]string, error)
 	return names, nil
 }
 
+// Update applies fn to the entry at logicalPath within a single read-write transaction
+func (b *BoltStorage) Update(ctx context.Context, logicalPath string, fn UpdateFunc) error {
+	ctx, span := b.tracer.Start(ctx, "BoltStorage Update", trace.WithAttributes(attribute.String("realm.bolt.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("update operation")
+
+	if err := ValidatePath(logicalPath); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	path := path.Clean(logicalPath)
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return ctx.Err()
+	default:
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte("chambers"))
+
+		var current *StorageEntry
+		// values returned by bolt are only valid for the life of the transaction
+		if value := bucket.Get([]byte(path)); value != nil {
+			current = &StorageEntry{Key: logicalPath, Value: bytes.Clone(value)}
+		}
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			return bucket.Delete([]byte(path))
+		}
+		return bucket.Put([]byte(path), []byte(next.Value))
+	})
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	return nil
+}
+
 func (b *BoltStorage) Close(ctx context.Context) error {
 	return b.db.Close()
 }
@@ -22,6 +22,7 @@ type CacheableStorage struct {
 
 var (
 	_ Storage = (*CacheableStorage)(nil)
+	_ Updater = (*CacheableStorage)(nil)
 )
 
 // NewCacheableStorage returns a write-through cacheable storage.
@@ -162,6 +163,43 @@ func (c *CacheableStorage) List(ctx context.Context, prefix string) ([]string, e
 	return c.source.List(ctx, prefix)
 }
 
+// Update applies fn to the entry of the source layer and writes the result through to the cache layer
+func (c *CacheableStorage) Update(ctx context.Context, logicalPath string, fn UpdateFunc) error {
+	ctx, span := c.tracer.Start(ctx, "CacheableStorage Update", trace.WithAttributes(attribute.String("realm.cacheable.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("update operation")
+
+	var next *StorageEntry
+	err := Update(ctx, c.source, logicalPath, func(current *StorageEntry) (*StorageEntry, error) {
+		var err error
+		next, err = fn(current)
+		return next, err
+	})
+	if err != nil {
+		span.RecordError(err, trace.WithAttributes(attribute.String("realm.cacheable.origin", "source")))
+		return err
+	}
+
+	var cacheErr error
+	if next == nil {
+		cacheErr = c.cache.Delete(ctx, logicalPath)
+		var nfError *NotFoundError
+		if errors.As(cacheErr, &nfError) {
+			cacheErr = nil
+		}
+	} else {
+		cacheErr = c.cache.Put(ctx, StorageEntry{Key: logicalPath, Value: next.Value})
+	}
+	// the source was updated so the cache error is only logged like with Put
+	if cacheErr != nil {
+		span.RecordError(cacheErr, trace.WithAttributes(attribute.String("realm.cacheable.origin", "cache")))
+		logger.ErrorCtx(ctx).Str("error", cacheErr.Error()).Msg("failed to write to cache")
+	}
+	return nil
+}
+
 func (c *CacheableStorage) Close(ctx context.Context) error {
 	if err := c.cache.Close(ctx); err != nil {
 		return err
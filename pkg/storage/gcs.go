@@ -3,8 +3,10 @@ package storage
 import (
 	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"path"
 	"slices"
 	"strings"
@@ -14,6 +16,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 )
 
@@ -25,9 +28,14 @@ type GCSStorage struct {
 
 var (
 	_ Storage = (*GCSStorage)(nil)
+	_ Updater = (*GCSStorage)(nil)
 )
 
-const gcsEntryKey string = "entry"
+const (
+	gcsEntryKey string = "entry"
+	// gcsUpdateAttempts is how many times an update is attempted when the object is modified concurrently
+	gcsUpdateAttempts = 5
+)
 
 func NewGCSStorage(conf map[string]string) (Storage, error) {
 	if conf["bucket"] == "" {
@@ -108,17 +116,7 @@ func (s *GCSStorage) Put(ctx context.Context, e StorageEntry) (retErr error) {
 
 	p, key := s.expandPath(e.Key + gcsEntryKey)
 
-	w := s.client.Bucket(s.bucket).Object(path.Join(p, key)).NewWriter(ctx)
-	md5Array := md5.Sum(e.Value)
-	w.MD5 = md5Array[:]
-	w.ContentType = "application/json"
-
-	if _, err := w.Write(e.Value); err != nil {
-		span.RecordError((err))
-		return fmt.Errorf("failed to put: %w", err)
-	}
-
-	if err := w.Close(); err != nil {
+	if err := writeObject(ctx, s.client.Bucket(s.bucket).Object(path.Join(p, key)), e.Value); err != nil {
 		span.RecordError((err))
 		return fmt.Errorf("failed to put: %w", err)
 	}
@@ -126,6 +124,19 @@ func (s *GCSStorage) Put(ctx context.Context, e StorageEntry) (retErr error) {
 	return nil
 }
 
+func writeObject(ctx context.Context, obj *gcs.ObjectHandle, value []byte) error {
+	w := obj.NewWriter(ctx)
+	md5Array := md5.Sum(value)
+	w.MD5 = md5Array[:]
+	w.ContentType = "application/json"
+
+	if _, err := w.Write(value); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 func (s *GCSStorage) Delete(ctx context.Context, logicalPath string) error {
 	ctx, span := s.tracer.Start(ctx, "GCSStorage Delete", trace.WithAttributes(attribute.String("realm.gcs.logicalPath", logicalPath)))
 	defer span.End()
@@ -212,6 +223,76 @@ func (s *GCSStorage) List(ctx context.Context, prefix string) ([]string, error)
 	return keys, nil
 }
 
+// Update applies fn to the entry at logicalPath, writing the result only if the object's generation did not change since it was read.
+// The update is retried when the object was modified concurrently
+func (s *GCSStorage) Update(ctx context.Context, logicalPath string, fn UpdateFunc) error {
+	ctx, span := s.tracer.Start(ctx, "GCSStorage Update", trace.WithAttributes(attribute.String("realm.gcs.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("update operation")
+
+	if err := ValidatePath(logicalPath); err != nil {
+		span.RecordError((err))
+		return err
+	}
+
+	p, key := s.expandPath(logicalPath + gcsEntryKey)
+	obj := s.client.Bucket(s.bucket).Object(path.Join(p, key))
+
+	for attempt := 1; ; attempt++ {
+		current, conds, err := s.readForUpdate(ctx, obj, logicalPath)
+		if err != nil {
+			span.RecordError((err))
+			return err
+		}
+
+		next, err := fn(current)
+		if err != nil {
+			span.RecordError((err))
+			return err
+		}
+
+		switch {
+		case next == nil && current == nil:
+			return nil
+		case next == nil:
+			err = obj.If(conds).Delete(ctx)
+		default:
+			err = writeObject(ctx, obj.If(conds), next.Value)
+		}
+		if err == nil {
+			return nil
+		}
+
+		var gErr *googleapi.Error
+		if errors.As(err, &gErr) && gErr.Code == http.StatusPreconditionFailed && attempt < gcsUpdateAttempts {
+			logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Int("attempt", attempt).Msg("object was modified concurrently, retrying update")
+			continue
+		}
+		span.RecordError((err))
+		return fmt.Errorf("failed to update: %w", err)
+	}
+}
+
+// readForUpdate returns the entry of obj, if it exists, along with the conditions under which it is unchanged
+func (s *GCSStorage) readForUpdate(ctx context.Context, obj *gcs.ObjectHandle, logicalPath string) (*StorageEntry, gcs.Conditions, error) {
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, gcs.ErrObjectNotExist) {
+			return nil, gcs.Conditions{DoesNotExist: true}, nil
+		}
+		return nil, gcs.Conditions{}, err
+	}
+	defer r.Close()
+
+	value, err := io.ReadAll(r)
+	if err != nil {
+		return nil, gcs.Conditions{}, err
+	}
+	return &StorageEntry{Key: logicalPath, Value: value}, gcs.Conditions{GenerationMatch: r.Attrs.Generation}, nil
+}
+
 func (s *GCSStorage) Close(ctx context.Context) error {
 	return s.client.Close()
 }
@@ -22,6 +22,7 @@ type InheritableStorage struct {
 
 var (
 	_ Storage = (*InheritableStorage)(nil)
+	_ Updater = (*InheritableStorage)(nil)
 )
 
 // NewInheritableStorage returns a InheritableStorage with the source Storage
@@ -34,6 +35,13 @@ func NewInheritableStorage(source Storage) (Storage, error) {
 
 // Get gets the entry at the specified logical path, inheriting all parent chambers if they exist
 func (s *InheritableStorage) Get(ctx context.Context, logicalPath string) (*StorageEntry, error) {
+	entry, _, err := s.GetWithLeaf(ctx, logicalPath)
+	return entry, err
+}
+
+// GetWithLeaf gets the entry at the specified logical path like Get, along with the entry as it is stored in the source
+// without its parent chambers, both from the same read of the source
+func (s *InheritableStorage) GetWithLeaf(ctx context.Context, logicalPath string) (*StorageEntry, *StorageEntry, error) {
 	ctx, span := s.tracer.Start(ctx, "InheritableStorage Get", trace.WithAttributes(attribute.String("realm.inheritable.logicalPath", logicalPath)))
 	defer span.End()
 
@@ -42,20 +50,20 @@ func (s *InheritableStorage) Get(ctx context.Context, logicalPath string) (*Stor
 
 	if err := ValidatePath(logicalPath); err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	// ensure the last entry of the path exists before retrieving its parents
 	leafEntry, err := s.source.Get(ctx, logicalPath)
 	if err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	leaf := &realm.Chamber{}
 	if err := json.Unmarshal(leafEntry.Value, leaf); err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	clean := path.Clean(logicalPath)
@@ -91,17 +99,17 @@ func (s *InheritableStorage) Get(ctx context.Context, logicalPath string) (*Stor
 	select {
 	case <-ctx.Done():
 		span.RecordError(ctx.Err())
-		return nil, ctx.Err()
+		return nil, nil, ctx.Err()
 	default:
 	}
 
 	buf := new(bytes.Buffer)
 	if err := utils.WriteInterfaceWith(buf, leaf, false); err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	return &StorageEntry{Key: logicalPath, Value: buf.Bytes()}, nil
+	return &StorageEntry{Key: logicalPath, Value: buf.Bytes()}, leafEntry, nil
 }
 
 // Put puts the entry at the specified logical path, creating or overwriting it
@@ -189,6 +197,26 @@ func (s *InheritableStorage) List(ctx context.Context, prefix string) ([]string,
 	return names, nil
 }
 
+// Update applies fn to the entry of the source storage at the specified logical path, without the rules of its parents
+func (s *InheritableStorage) Update(ctx context.Context, logicalPath string, fn UpdateFunc) error {
+	ctx, span := s.tracer.Start(ctx, "InheritableStorage Update", trace.WithAttributes(attribute.String("realm.inheritable.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("update operation")
+
+	if err := Update(ctx, s.source, logicalPath, fn); err != nil {
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// Source returns the storage that chambers are retrieved from without inheriting the rules of their parents
+func (s *InheritableStorage) Source() Storage {
+	return s.source
+}
+
 // Close closes the source storage
 func (s *InheritableStorage) Close(ctx context.Context) error {
 	return s.source.Close(ctx)
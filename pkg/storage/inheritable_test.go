@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"slices"
 	"testing"
@@ -40,3 +41,32 @@ func TestListShouldForwardToSource(t *testing.T) {
 		t.Errorf("did not correctly retrieve from source: %v, expected: %v", entry, expected)
 	}
 }
+
+func TestGetWithLeaf(t *testing.T) {
+	source, err := NewInmemStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []StorageEntry{
+		{Key: "/org/", Value: []byte(`{"rules":{"enabled":{"type":"boolean","value":true}}}`)},
+		{Key: "/org/app/", Value: []byte(`{"rules":{"message":{"type":"string","value":"hello"}}}`)},
+	}
+	for _, e := range entries {
+		if err := source.Put(context.TODO(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stg, _ := NewInheritableStorage(source)
+	s := stg.(*InheritableStorage)
+	entry, leaf, err := s.GetWithLeaf(context.TODO(), "/org/app/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(leaf.Value, entries[1].Value) {
+		t.Errorf("expected the stored leaf %s but returned %s", entries[1].Value, leaf.Value)
+	}
+	if !bytes.Contains(entry.Value, []byte(`"enabled"`)) || !bytes.Contains(entry.Value, []byte(`"message"`)) {
+		t.Errorf("expected the entry to inherit its parent but returned %s", entry.Value)
+	}
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/steviebps/realm/helper/logging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InmemStorage is a map backed storage that does not persist entries.
+// It is intended for tests and local development
+type InmemStorage struct {
+	sync.RWMutex
+	entries map[string][]byte
+	tracer  trace.Tracer
+}
+
+var (
+	_ Storage = (*InmemStorage)(nil)
+	_ Updater = (*InmemStorage)(nil)
+)
+
+// NewInmemStorage creates a new in-memory storage backend
+func NewInmemStorage(conf map[string]string) (Storage, error) {
+	return &InmemStorage{
+		entries: make(map[string][]byte),
+		tracer:  otel.Tracer("github.com/steviebps/realm"),
+	}, nil
+}
+
+func (s *InmemStorage) Get(ctx context.Context, logicalPath string) (*StorageEntry, error) {
+	ctx, span := s.tracer.Start(ctx, "InmemStorage Get", trace.WithAttributes(attribute.String("realm.inmem.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("get operation")
+
+	if err := ValidatePath(logicalPath); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	s.RLock()
+	value, ok := s.entries[path.Clean(logicalPath)]
+	s.RUnlock()
+	if !ok {
+		err := &NotFoundError{logicalPath}
+		span.RecordError(err)
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return nil, ctx.Err()
+	default:
+	}
+
+	return &StorageEntry{Key: logicalPath, Value: slices.Clone(value)}, nil
+}
+
+func (s *InmemStorage) Put(ctx context.Context, e StorageEntry) error {
+	ctx, span := s.tracer.Start(ctx, "InmemStorage Put", trace.WithAttributes(attribute.String("realm.inmem.entry.key", e.Key)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", e.Key).Msg("put operation")
+
+	if err := ValidatePath(e.Key); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return ctx.Err()
+	default:
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.entries[path.Clean(e.Key)] = slices.Clone(e.Value)
+	return nil
+}
+
+func (s *InmemStorage) Delete(ctx context.Context, logicalPath string) error {
+	ctx, span := s.tracer.Start(ctx, "InmemStorage Delete", trace.WithAttributes(attribute.String("realm.inmem.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("delete operation")
+
+	if err := ValidatePath(logicalPath); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return ctx.Err()
+	default:
+	}
+
+	key := path.Clean(logicalPath)
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.entries[key]; !ok {
+		err := &NotFoundError{logicalPath}
+		span.RecordError(err)
+		return err
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *InmemStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, span := s.tracer.Start(ctx, "InmemStorage List", trace.WithAttributes(attribute.String("realm.inmem.prefix", prefix)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("prefix", prefix).Msg("list operation")
+
+	if err := ValidatePath(prefix); err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	cleanPrefix := path.Clean(prefix)
+	if !strings.HasSuffix(cleanPrefix, "/") {
+		cleanPrefix += "/"
+	}
+
+	set := make(map[string]struct{})
+	s.RLock()
+	for k := range s.entries {
+		key, ok := strings.CutPrefix(k, cleanPrefix)
+		if !ok {
+			continue
+		}
+		before, _, _ := strings.Cut(key, "/")
+		if len(before) > 0 {
+			set[before] = struct{}{}
+		}
+	}
+	s.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return nil, ctx.Err()
+	default:
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+func (s *InmemStorage) Update(ctx context.Context, logicalPath string, fn UpdateFunc) error {
+	ctx, span := s.tracer.Start(ctx, "InmemStorage Update", trace.WithAttributes(attribute.String("realm.inmem.logicalPath", logicalPath)))
+	defer span.End()
+
+	logger := logging.Ctx(ctx)
+	logger.DebugCtx(ctx).Str("logicalPath", logicalPath).Msg("update operation")
+
+	if err := ValidatePath(logicalPath); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		span.RecordError(ctx.Err())
+		return ctx.Err()
+	default:
+	}
+
+	key := path.Clean(logicalPath)
+	s.Lock()
+	defer s.Unlock()
+
+	var current *StorageEntry
+	if value, ok := s.entries[key]; ok {
+		current = &StorageEntry{Key: logicalPath, Value: slices.Clone(value)}
+	}
+	next, err := fn(current)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+	if next == nil {
+		delete(s.entries, key)
+		return nil
+	}
+	s.entries[key] = slices.Clone(next.Value)
+	return nil
+}
+
+func (s *InmemStorage) Close(ctx context.Context) error {
+	return nil
+}
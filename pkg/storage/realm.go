@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	realm "github.com/steviebps/realm/pkg"
+	"github.com/steviebps/realm/utils"
+)
+
+// ChamberGetter retrieves chambers directly from a Storage so that a Realm can run without a realm server
+type ChamberGetter struct {
+	storage Storage
+}
+
+var (
+	_ realm.ChamberGetter = (*ChamberGetter)(nil)
+)
+
+// NewChamberGetter returns a ChamberGetter that reads chambers from s.
+// When inheritable is true, chambers inherit the rules of their parents like with InheritableStorage
+func NewChamberGetter(s Storage, inheritable bool) (*ChamberGetter, error) {
+	if s == nil {
+		return nil, fmt.Errorf("storage cannot be nil")
+	}
+	if inheritable {
+		var err error
+		if s, err = NewInheritableStorage(s); err != nil {
+			return nil, err
+		}
+	}
+	return &ChamberGetter{storage: s}, nil
+}
+
+// WithStorage makes a Realm retrieve its chambers from s instead of a realm server, e.g. for batch jobs or functions.
+// Polling and change detection work the same way as with a realm server.
+// It is defined in this package rather than realm's since storage depends on realm
+func WithStorage(s Storage, inheritable bool) (realm.RealmOption, error) {
+	g, err := NewChamberGetter(s, inheritable)
+	if err != nil {
+		return nil, err
+	}
+	return realm.WithChamberGetter(g), nil
+}
+
+// GetChamber retrieves the chamber at path, which is resolved the same way as the realm server resolves the paths of requests
+func (g *ChamberGetter) GetChamber(ctx context.Context, path string) (*realm.Chamber, error) {
+	logicalPath := utils.EnsureTrailingSlash("/" + strings.TrimPrefix(path, "/"))
+	entry, err := g.storage.Get(ctx, logicalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var c realm.Chamber
+	if err := json.Unmarshal(entry.Value, &c); err != nil {
+		return nil, fmt.Errorf("could not unmarshal chamber %q: %w", logicalPath, err)
+	}
+	return &c, nil
+}
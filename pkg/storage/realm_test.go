@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	realm "github.com/steviebps/realm/pkg"
+)
+
+func putChamber(t *testing.T, s Storage, path string, raw string) {
+	t.Helper()
+	if err := s.Put(context.Background(), StorageEntry{Key: path, Value: []byte(raw)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithStorage(t *testing.T) {
+	tests := []struct {
+		name        string
+		inheritable bool
+		want        string
+	}{
+		{"direct", false, "DEFAULT"},
+		{"inheritable", true, "parent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := NewInmemStorage(nil)
+			putChamber(t, s, "/root/", `{"rules":{"message":{"type":"string","value":"parent"}}}`)
+			putChamber(t, s, "/root/child/", `{"rules":{"port":{"type":"number","value":3000}}}`)
+
+			opt, err := WithStorage(s, tt.inheritable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rlm, err := realm.NewRealm(opt, realm.WithPath("root/child"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := rlm.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer rlm.Stop(context.Background())
+
+			ctx := rlm.NewContext(context.Background())
+			if port, _ := rlm.Float64(ctx, "port", 0); port != 3000 {
+				t.Errorf("port = %v, expected 3000", port)
+			}
+			if message, _ := rlm.String(ctx, "message", "DEFAULT"); message != tt.want {
+				t.Errorf("message = %q, expected %q", message, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithStorageDetectsChanges(t *testing.T) {
+	s, _ := NewInmemStorage(nil)
+	putChamber(t, s, "/root/", `{"rules":{"message":{"type":"string","value":"before"}}}`)
+
+	opt, err := WithStorage(s, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rlm, err := realm.NewRealm(opt, realm.WithPath("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	var events []realm.Event
+	unsubscribe := rlm.Subscribe(func(e realm.Event) { events = append(events, e) })
+	defer unsubscribe()
+
+	putChamber(t, s, "/root/", `{"rules":{"message":{"type":"string","value":"after"}}}`)
+	if err := rlm.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Type != realm.EventChanged || len(events[0].ChangedKeys) != 1 || events[0].ChangedKeys[0] != "message" {
+		t.Errorf("events = %+v, expected a single change of message", events)
+	}
+	if message, _ := rlm.String(rlm.NewContext(context.Background()), "message", "DEFAULT"); message != "after" {
+		t.Errorf("message = %q, expected %q", message, "after")
+	}
+}
+
+func TestChamberGetterNotFound(t *testing.T) {
+	s, _ := NewInmemStorage(nil)
+	g, err := NewChamberGetter(s, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.GetChamber(context.Background(), "missing")
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("err = %v, expected a NotFoundError", err)
+	}
+}
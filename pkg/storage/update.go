@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+)
+
+// UpdateFunc returns the new entry from the current entry at a key, which is nil when there is none.
+// Returning a nil entry deletes the current entry and returning an error aborts the update
+type UpdateFunc func(current *StorageEntry) (*StorageEntry, error)
+
+// Updater is implemented by storage backends that can read and write an entry atomically
+type Updater interface {
+	// Update replaces the entry at key with the result of fn without any other write to key in between
+	Update(ctx context.Context, key string, fn UpdateFunc) error
+}
+
+// Update atomically replaces the entry at key with the result of fn.
+// Storage backends that do not implement Updater are only updated atomically with the other updates of this process
+func Update(ctx context.Context, s Storage, key string, fn UpdateFunc) error {
+	if err := ValidatePath(key); err != nil {
+		return err
+	}
+	if u, ok := s.(Updater); ok {
+		return u.Update(ctx, key, fn)
+	}
+
+	unlock := updateLocks.lock(path.Clean(key))
+	defer unlock()
+
+	current, err := s.Get(ctx, key)
+	if err != nil {
+		var nfError *NotFoundError
+		if !errors.As(err, &nfError) {
+			return err
+		}
+		current = nil
+	}
+
+	next, err := fn(current)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		if current == nil {
+			return nil
+		}
+		return s.Delete(ctx, key)
+	}
+	next.Key = key
+	return s.Put(ctx, *next)
+}
+
+// updateLocks serializes the updates of storage backends that do not implement Updater
+var updateLocks = &keyedMutex{locks: make(map[string]*keyedLock)}
+
+type keyedLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// keyedMutex is a mutex per key whose locks are released once unused
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+func (km *keyedMutex) lock(key string) func() {
+	km.mu.Lock()
+	l, ok := km.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		km.locks[key] = l
+	}
+	l.refs++
+	km.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		km.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}
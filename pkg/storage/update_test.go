@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	backends := map[string]func(t *testing.T) Storage{
+		"inmem": func(t *testing.T) Storage {
+			s, _ := NewInmemStorage(nil)
+			return s
+		},
+		"boltdb": func(t *testing.T) Storage {
+			s, err := NewBoltStorage(map[string]string{"path": t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		"file": func(t *testing.T) Storage {
+			s, _ := NewFileStorage(map[string]string{"path": t.TempDir()})
+			return s
+		},
+	}
+
+	for name, newStorage := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := newStorage(t)
+			defer s.Close(context.Background())
+			ctx := context.Background()
+
+			increment := func(current *StorageEntry) (*StorageEntry, error) {
+				n := 0
+				if current != nil {
+					var err error
+					if n, err = strconv.Atoi(strings.TrimSpace(string(current.Value))); err != nil {
+						return nil, err
+					}
+				}
+				return &StorageEntry{Value: []byte(strconv.Itoa(n + 1))}, nil
+			}
+
+			var wg sync.WaitGroup
+			for range 50 {
+				wg.Go(func() {
+					if err := Update(ctx, s, "/counter/", increment); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+			wg.Wait()
+
+			entry, err := s.Get(ctx, "/counter/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(entry.Value)) != "50" {
+				t.Errorf("expected every update to be applied but value is %s", entry.Value)
+			}
+
+			abort := errors.New("abort")
+			err = Update(ctx, s, "/counter/", func(current *StorageEntry) (*StorageEntry, error) { return nil, abort })
+			if !errors.Is(err, abort) {
+				t.Errorf("expected the error of the update but returned %v", err)
+			}
+
+			if err := Update(ctx, s, "/counter/", func(current *StorageEntry) (*StorageEntry, error) { return nil, nil }); err != nil {
+				t.Fatal(err)
+			}
+			var nfError *NotFoundError
+			if _, err := s.Get(ctx, "/counter/"); !errors.As(err, &nfError) {
+				t.Errorf("expected the entry to be deleted but returned %v", err)
+			}
+		})
+	}
+}
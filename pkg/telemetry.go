@@ -0,0 +1,236 @@
+package realm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultEvaluationMetricKeyLimit is the default number of distinct rule keys recorded by the evaluation counter
+	DefaultEvaluationMetricKeyLimit = 250
+	// evaluationMetricOtherKey replaces the rule key of evaluations recorded after the key limit was reached
+	evaluationMetricOtherKey = "_OTHER"
+	// evaluationEventName is the name of the span event of the OpenTelemetry feature flag semantic conventions
+	evaluationEventName   = "feature_flag.evaluation"
+	telemetryProviderName = "realm"
+)
+
+// evaluationTelemetry records every evaluation as a metric and, for sampled evaluations, as an event of the active span
+type evaluationTelemetry struct {
+	counter metric.Int64Counter
+	// revisionMismatches counts contexts created for callers that evaluated a different revision of the primary chamber
+	revisionMismatches metric.Int64Counter
+	sampleRatio        float64
+	keyLimit           int
+
+	mu sync.RWMutex
+	// keys holds the rule keys that are recorded as an attribute of the counter
+	keys map[string]struct{}
+	// attrs caches the attribute sets of the counter so that recording an evaluation does not allocate
+	attrs map[evaluationAttrs]attribute.Set
+}
+
+type evaluationAttrs struct {
+	key       string
+	setID     string
+	reason    Reason
+	variant   string
+	errorType string
+}
+
+// WithMeterProvider sets the meter provider used to record evaluations. The global meter provider is used by default
+func WithMeterProvider(mp metric.MeterProvider) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.meterProvider = mp
+		return rc
+	})
+}
+
+// WithEvaluationEventSampling sets the fraction of evaluations, between 0 and 1, that are added as an event to the active span.
+// All evaluations are sampled by default and a ratio of 0 disables span events
+func WithEvaluationEventSampling(ratio float64) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.evaluationEventSampling = &ratio
+		return rc
+	})
+}
+
+// WithEvaluationMetricKeyLimit sets the number of distinct rule keys recorded by the evaluation counter.
+// Evaluations of keys beyond the limit are recorded with the key "_OTHER".
+// A negative limit records every evaluation with the key "_OTHER"
+func WithEvaluationMetricKeyLimit(limit int) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.evaluationMetricKeyLimit = limit
+		return rc
+	})
+}
+
+func newEvaluationTelemetry(cfg RealmConfig) (*evaluationTelemetry, error) {
+	mp := cfg.meterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+
+	meter := mp.Meter("github.com/steviebps/realm")
+	counter, err := meter.Int64Counter(
+		"realm.rule.evaluations",
+		metric.WithDescription("The number of rule evaluations"),
+		metric.WithUnit("{evaluation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionMismatches, err := meter.Int64Counter(
+		"realm.revision.mismatches",
+		metric.WithDescription("The number of requests whose caller evaluated a different revision of the primary chamber"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := 1.0
+	if cfg.evaluationEventSampling != nil {
+		ratio = min(max(*cfg.evaluationEventSampling, 0), 1)
+	}
+	limit := cfg.evaluationMetricKeyLimit
+	if limit == 0 {
+		limit = DefaultEvaluationMetricKeyLimit
+	}
+
+	return &evaluationTelemetry{
+		counter:            counter,
+		revisionMismatches: revisionMismatches,
+		sampleRatio:        ratio,
+		keyLimit:           limit,
+		keys:               make(map[string]struct{}),
+		attrs:              make(map[evaluationAttrs]attribute.Set),
+	}, nil
+}
+
+// record records the evaluation of a rule from the chamber at setID
+func (t *evaluationTelemetry) record(ctx context.Context, setID string, e Evaluation) {
+	errType := evaluationErrorType(e.Err)
+	t.counter.Add(ctx, 1, metric.WithAttributeSet(t.attributeSet(evaluationAttrs{
+		key:       t.metricKey(e.Key),
+		setID:     setID,
+		reason:    e.Reason,
+		variant:   e.Variant,
+		errorType: errType,
+	})))
+
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() || !t.sampled() {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.FeatureFlagKey(e.Key),
+		semconv.FeatureFlagProviderName(telemetryProviderName),
+		semconv.FeatureFlagResultReasonKey.String(strings.ToLower(string(e.Reason))),
+	}
+	if e.Variant != "" {
+		attrs = append(attrs, semconv.FeatureFlagResultVariant(e.Variant))
+	}
+	if setID != "" {
+		attrs = append(attrs, semconv.FeatureFlagSetID(setID))
+	}
+	if e.Err != nil {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errType), semconv.ErrorMessage(e.Err.Error()))
+	}
+	span.AddEvent(evaluationEventName, trace.WithAttributes(attrs...))
+}
+
+func (t *evaluationTelemetry) sampled() bool {
+	return t.sampleRatio >= 1 || (t.sampleRatio > 0 && rand.Float64() < t.sampleRatio)
+}
+
+// metricKey returns the rule key to record with the counter, limiting the number of distinct keys
+func (t *evaluationTelemetry) metricKey(key string) string {
+	if t.keyLimit < 0 {
+		return evaluationMetricOtherKey
+	}
+
+	t.mu.RLock()
+	_, ok := t.keys[key]
+	full := len(t.keys) >= t.keyLimit
+	t.mu.RUnlock()
+	if ok {
+		return key
+	}
+	if full {
+		return evaluationMetricOtherKey
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.keys[key]; !ok && len(t.keys) >= t.keyLimit {
+		return evaluationMetricOtherKey
+	}
+	t.keys[key] = struct{}{}
+	return key
+}
+
+func (t *evaluationTelemetry) attributeSet(a evaluationAttrs) attribute.Set {
+	t.mu.RLock()
+	set, ok := t.attrs[a]
+	t.mu.RUnlock()
+	if ok {
+		return set
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.FeatureFlagKey(a.key),
+		semconv.FeatureFlagProviderName(telemetryProviderName),
+		semconv.FeatureFlagResultReasonKey.String(strings.ToLower(string(a.reason))),
+		semconv.FeatureFlagResultVariant(a.variant),
+		semconv.FeatureFlagSetID(a.setID),
+	}
+	if a.errorType != "" {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(a.errorType))
+	}
+	set = attribute.NewSet(attrs...)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attrs[a] = set
+	return set
+}
+
+// evaluationErrorType returns the OpenFeature error code of an evaluation error
+func evaluationErrorType(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var rnf *ErrRuleNotFound
+	var cnc *ErrCouldNotConvertRule
+	switch {
+	case errors.As(err, &rnf):
+		return "flag_not_found"
+	case errors.As(err, &cnc):
+		return "type_mismatch"
+	case errors.Is(err, ErrChamberEmpty):
+		return "provider_not_ready"
+	default:
+		return "general"
+	}
+}
+
+// evaluationSetID returns the path of the chamber the evaluation was retrieved from
+func (rlm *Realm) evaluationSetID(e Evaluation) string {
+	if e.Namespace == "" {
+		return rlm.path
+	}
+	return rlm.namespaces[e.Namespace]
+}
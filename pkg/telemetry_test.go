@@ -0,0 +1,86 @@
+package realm
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEvaluationTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	g := &staticGetter{chamber: `{"rules":{
+		"enabled":{"type":"boolean","value":true},
+		"message":{"type":"string","value":"hello","overrides":[{"type":"string","value":"new","minimumVersion":"v1.0.0","maximumVersion":"v2.0.0"}]}
+	}}`}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/"), WithVersion("v1.5.0"), WithMeterProvider(mp), WithEvaluationMetricKeyLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "test")
+	rlm.Bool(ctx, "enabled", false)
+	rlm.Bool(ctx, "enabled", false)
+	rlm.String(ctx, "message", "")
+	rlm.Bool(ctx, "missing", false)
+	span.End()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || m.Name != "realm.rule.evaluations" {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				key, _ := dp.Attributes.Value("feature_flag.key")
+				reason, _ := dp.Attributes.Value("feature_flag.result.reason")
+				variant, _ := dp.Attributes.Value("feature_flag.result.variant")
+				counts[key.AsString()+"|"+reason.AsString()+"|"+variant.AsString()] += dp.Value
+			}
+		}
+	}
+
+	expected := map[string]int64{
+		"enabled|static|default":                          2,
+		"message|targeting_match|override[v1.0.0,v2.0.0]": 1,
+		"_OTHER|error|":                                   1,
+	}
+	for k, v := range expected {
+		if counts[k] != v {
+			t.Errorf("expected %d evaluations of %q but recorded %d, recorded: %v", v, k, counts[k], counts)
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span but recorded %d", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 4 {
+		t.Fatalf("expected 4 span events but recorded %d", len(events))
+	}
+	last := attribute.NewSet(events[3].Attributes...)
+	if v, _ := last.Value("feature_flag.key"); events[3].Name != "feature_flag.evaluation" || v.AsString() != "missing" {
+		t.Errorf("unexpected span event %q with attributes %v", events[3].Name, events[3].Attributes)
+	}
+	if v, _ := last.Value("error.type"); v.AsString() != "flag_not_found" {
+		t.Errorf("expected error.type flag_not_found but recorded %q", v.AsString())
+	}
+}
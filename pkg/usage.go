@@ -0,0 +1,132 @@
+package realm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/steviebps/realm/api"
+)
+
+const (
+	// DefaultUsageReportInterval is used as the default interval between usage reports
+	DefaultUsageReportInterval = time.Minute
+	// usageReportTimeout limits the final usage report sent when realm is stopped
+	usageReportTimeout = 5 * time.Second
+)
+
+// UsageReporter sends usage reports to a realm server. *client.HttpClient is a UsageReporter
+type UsageReporter interface {
+	ReportUsage(ctx context.Context, report api.UsageReport) error
+}
+
+// usageTracker aggregates the number of evaluations of each rule between usage reports
+type usageTracker struct {
+	reporter    UsageReporter
+	application string
+	interval    time.Duration
+
+	mu sync.Mutex
+	// counts maps a chamber path to the number of evaluations of each of its rules
+	counts map[string]map[string]int64
+}
+
+// WithUsageReporting enables periodically reporting how many times each rule was evaluated to the realm server,
+// so that rules that are no longer read can be found. The report is tagged with the application name and the version set with WithVersion.
+// DefaultUsageReportInterval is used when interval is not positive
+func WithUsageReporting(application string, interval time.Duration) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.usageApplication = application
+		rc.usageInterval = interval
+		return rc
+	})
+}
+
+// WithUsageReporter sets where usage reports are sent when usage reporting is enabled.
+// The http client set with WithHttpClient is used by default
+func WithUsageReporter(reporter UsageReporter) RealmOption {
+	return realmOptionFunc(func(rc RealmConfig) RealmConfig {
+		rc.usageReporter = reporter
+		return rc
+	})
+}
+
+func (ut *usageTracker) record(path string, key string) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	rules, ok := ut.counts[path]
+	if !ok {
+		rules = make(map[string]int64)
+		ut.counts[path] = rules
+	}
+	rules[key]++
+}
+
+// take returns the counts aggregated since the previous call and resets them
+func (ut *usageTracker) take() map[string]map[string]int64 {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	counts := ut.counts
+	ut.counts = make(map[string]map[string]int64)
+	return counts
+}
+
+// restore adds counts that could not be reported back so they are included in the next report
+func (ut *usageTracker) restore(counts map[string]map[string]int64) {
+	ut.mu.Lock()
+	defer ut.mu.Unlock()
+
+	for path, rules := range counts {
+		current, ok := ut.counts[path]
+		if !ok {
+			ut.counts[path] = rules
+			continue
+		}
+		for key, n := range rules {
+			current[key] += n
+		}
+	}
+}
+
+// recordUsage counts the evaluation if the rule exists
+func (rlm *Realm) recordUsage(e Evaluation) {
+	if rlm.usage == nil || e.Type == "" {
+		return
+	}
+	rlm.usage.record(rlm.evaluationSetID(e), e.Key)
+}
+
+// reportUsage sends the usage aggregated since the previous report
+func (rlm *Realm) reportUsage(ctx context.Context) error {
+	counts := rlm.usage.take()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	report := api.UsageReport{Application: rlm.usage.application, Version: rlm.applicationVersion, Chambers: counts}
+	if err := rlm.usage.reporter.ReportUsage(ctx, report); err != nil {
+		rlm.usage.restore(counts)
+		rlm.logger.ErrorCtx(ctx).Str("error", err.Error()).Msg("could not report usage, it will be included in the next report")
+		return err
+	}
+	return nil
+}
+
+// reportUsagePeriodically reports usage every interval until realm is stopped, when the remaining usage is reported
+func (rlm *Realm) reportUsagePeriodically(ctx context.Context) {
+	ticker := time.NewTicker(rlm.usage.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rlm.stopCh:
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageReportTimeout)
+			rlm.reportUsage(ctx)
+			cancel()
+			return
+		case <-ticker.C:
+			rlm.reportUsage(ctx)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package realm
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/steviebps/realm/api"
+)
+
+type recordingReporter struct {
+	mu      sync.Mutex
+	fail    bool
+	reports []api.UsageReport
+}
+
+func (r *recordingReporter) ReportUsage(ctx context.Context, report api.UsageReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("server unavailable")
+	}
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestUsageReporting(t *testing.T) {
+	g := &staticGetter{chamber: `{"rules":{"enabled":{"type":"boolean","value":true},"message":{"type":"string","value":"hello"}}}`}
+	reporter := &recordingReporter{fail: true}
+	rlm, err := NewRealm(WithChamberGetter(g), WithPath("/"), WithVersion("v1.0.0"), WithUsageReporting("test-app", 0), WithUsageReporter(reporter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlm.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer rlm.Stop(context.Background())
+
+	ctx := context.Background()
+	rlm.Bool(ctx, "enabled", false)
+	rlm.Bool(ctx, "missing", false)
+	if err := rlm.reportUsage(ctx); err == nil {
+		t.Fatal("expected the report to fail")
+	}
+
+	reporter.fail = false
+	rlm.Bool(ctx, "enabled", false)
+	rlm.String(ctx, "message", "")
+	if err := rlm.reportUsage(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reporter.reports) != 1 {
+		t.Fatalf("expected 1 report but received %d", len(reporter.reports))
+	}
+	report := reporter.reports[0]
+	if report.Application != "test-app" || report.Version != "v1.0.0" {
+		t.Errorf("unexpected report tags: %q %q", report.Application, report.Version)
+	}
+	expected := map[string]int64{"enabled": 2, "message": 1}
+	if !maps.Equal(report.Chambers["/"], expected) {
+		t.Errorf("expected counts %v but reported %v", expected, report.Chambers)
+	}
+
+	if err := rlm.reportUsage(ctx); err != nil || len(reporter.reports) != 1 {
+		t.Errorf("empty usage should not be reported")
+	}
+}